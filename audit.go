@@ -0,0 +1,238 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultPendingThreshold is how long a resource may be pending before Audit
+// reports it.
+const DefaultPendingThreshold = time.Hour
+
+// FindingKind is the kind of problem an audit finding reports.
+type FindingKind string
+
+const (
+	// FindingDanglingReference is a link to a deleted datalake or source
+	// app, or a datalake or source app in a deleted hosting environment.
+	FindingDanglingReference FindingKind = "dangling_reference"
+	// FindingCrossEnvironmentLink is a link between a source app and a
+	// datalake in different hosting environments.
+	FindingCrossEnvironmentLink FindingKind = "cross_environment_link"
+	// FindingStuckPending is a resource pending for longer than the
+	// threshold.
+	FindingStuckPending FindingKind = "stuck_pending"
+	// FindingDisconnectedEnvironment is a disconnected hosting environment
+	// with connected source apps or ready datalakes.
+	FindingDisconnectedEnvironment FindingKind = "disconnected_environment"
+)
+
+// AuditOptions configures Audit.
+type AuditOptions struct {
+	// PendingThreshold is how long a resource may be pending, by default
+	// DefaultPendingThreshold.
+	PendingThreshold time.Duration
+	// Fix deletes links to deleted datalakes or source apps, after checking
+	// again that they are gone. Other findings need a human and are only
+	// reported.
+	Fix bool
+}
+
+// AuditFinding is a problem found by Audit.
+type AuditFinding struct {
+	Kind FindingKind `json:"kind"`
+	// ResourceKind is "hosting_environment", "datalake", "source_app" or
+	// "source_app_datalake_link".
+	ResourceKind string `json:"resource_kind"`
+	ResourceID   string `json:"resource_id"`
+	Message      string `json:"message"`
+	// Fixed is set when Fix resolved the finding.
+	Fixed bool `json:"fixed,omitempty"`
+}
+
+func (f AuditFinding) String() string {
+	s := fmt.Sprintf("%s: %s %s: %s", f.Kind, strings.ReplaceAll(f.ResourceKind, "_", " "), f.ResourceID, f.Message)
+	if f.Fixed {
+		s += " (fixed)"
+	}
+	return s
+}
+
+// AuditReport lists the findings of Audit ordered by kind and resource ID.
+type AuditReport struct {
+	Findings []AuditFinding `json:"findings"`
+}
+
+// OK reports whether the audit found no problems that are still open.
+func (r *AuditReport) OK() bool {
+	for _, f := range r.Findings {
+		if !f.Fixed {
+			return false
+		}
+	}
+	return true
+}
+
+// ByKind returns the findings of the given kind.
+func (r *AuditReport) ByKind(kind FindingKind) []AuditFinding {
+	var findings []AuditFinding
+	for _, f := range r.Findings {
+		if f.Kind == kind {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Audit walks all resources and reports inconsistencies between them. With
+// options.Fix set it also deletes dangling links; errors deleting them are
+// returned together with the report.
+func (c *Client) Audit(ctx context.Context, options AuditOptions, opts ...CallOption) (report *AuditReport, err error) {
+	ctx, end := c.telemetry.startOperation(ctx, "Audit")
+	defer func() { end(0, err) }()
+
+	inv, err := c.fetchInventory(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	threshold := options.PendingThreshold
+	if threshold <= 0 {
+		threshold = DefaultPendingThreshold
+	}
+	report = &AuditReport{Findings: auditInventory(inv, threshold, time.Now())}
+	if !options.Fix {
+		return report, nil
+	}
+
+	var errs []error
+	for i, f := range report.Findings {
+		if f.Kind != FindingDanglingReference || f.ResourceKind != "source_app_datalake_link" {
+			continue
+		}
+		fixed, err := c.deleteDanglingLink(ctx, f.ResourceID, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete link %s: %w", f.ResourceID, err))
+			continue
+		}
+		report.Findings[i].Fixed = fixed
+	}
+	return report, errors.Join(errs...)
+}
+
+// auditInventory returns the findings for a set of resources.
+func auditInventory(inv *inventory, threshold time.Duration, now time.Time) []AuditFinding {
+	environments := make(map[string]HostingEnvironment, len(inv.environments))
+	for _, e := range inv.environments {
+		environments[e.ID] = e
+	}
+	datalakes := make(map[string]Datalake, len(inv.datalakes))
+	for _, d := range inv.datalakes {
+		datalakes[d.ID] = d
+	}
+	sourceApps := make(map[string]SourceApp, len(inv.sourceApps))
+	for _, s := range inv.sourceApps {
+		sourceApps[s.ID] = s
+	}
+
+	findings := []AuditFinding{}
+	add := func(kind FindingKind, resourceKind, id, format string, args ...interface{}) {
+		findings = append(findings, AuditFinding{Kind: kind, ResourceKind: resourceKind, ResourceID: id, Message: fmt.Sprintf(format, args...)})
+	}
+	pending := func(resourceKind, id string, updatedAt time.Time) {
+		if age := now.Sub(updatedAt); age > threshold {
+			add(FindingStuckPending, resourceKind, id, "pending for %s", age.Truncate(time.Second))
+		}
+	}
+
+	for _, e := range inv.environments {
+		if e.Status == HostingEnvironmentStatusPending {
+			pending("hosting_environment", e.ID, e.UpdatedAt)
+		}
+	}
+	active := make(map[string]int)
+	for _, d := range inv.datalakes {
+		if _, ok := environments[d.HostingEnvironmentID]; !ok {
+			add(FindingDanglingReference, "datalake", d.ID, "hosting environment %s does not exist", d.HostingEnvironmentID)
+		}
+		switch d.Status {
+		case DatalakeStatusPending:
+			pending("datalake", d.ID, d.UpdatedAt)
+		case DatalakeStatusReady:
+			active[d.HostingEnvironmentID]++
+		}
+	}
+	for _, s := range inv.sourceApps {
+		if _, ok := environments[s.HostingEnvironmentID]; !ok {
+			add(FindingDanglingReference, "source_app", s.ID, "hosting environment %s does not exist", s.HostingEnvironmentID)
+		}
+		switch s.Status {
+		case SourceAppStatusPending:
+			pending("source_app", s.ID, s.UpdatedAt)
+		case SourceAppStatusConnected:
+			active[s.HostingEnvironmentID]++
+		}
+	}
+	for _, e := range inv.environments {
+		if e.Status == HostingEnvironmentStatusDisconnected && active[e.ID] > 0 {
+			add(FindingDisconnectedEnvironment, "hosting_environment", e.ID, "disconnected with %d connected source apps or ready datalakes", active[e.ID])
+		}
+	}
+
+	for _, l := range inv.links {
+		sourceApp, sourceAppOK := sourceApps[l.SourceAppID]
+		datalake, datalakeOK := datalakes[l.DatalakeID]
+		switch {
+		case !sourceAppOK && !datalakeOK:
+			add(FindingDanglingReference, "source_app_datalake_link", l.ID, "source app %s and datalake %s do not exist", l.SourceAppID, l.DatalakeID)
+		case !sourceAppOK:
+			add(FindingDanglingReference, "source_app_datalake_link", l.ID, "source app %s does not exist", l.SourceAppID)
+		case !datalakeOK:
+			add(FindingDanglingReference, "source_app_datalake_link", l.ID, "datalake %s does not exist", l.DatalakeID)
+		case sourceApp.HostingEnvironmentID != datalake.HostingEnvironmentID:
+			add(FindingCrossEnvironmentLink, "source_app_datalake_link", l.ID, "source app %s is in hosting environment %s but datalake %s is in %s",
+				sourceApp.ID, sourceApp.HostingEnvironmentID, datalake.ID, datalake.HostingEnvironmentID)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Kind != findings[j].Kind {
+			return findings[i].Kind < findings[j].Kind
+		}
+		return findings[i].ResourceID < findings[j].ResourceID
+	})
+	return findings
+}
+
+// deleteDanglingLink deletes a link after checking again that its source app
+// or datalake is gone, since it may have been recreated or the listing may
+// have been stale. It reports whether the link is gone.
+func (c *Client) deleteDanglingLink(ctx context.Context, id string, opts []CallOption) (bool, error) {
+	opts = appendOptions(opts, noCache())
+	link, err := c.GetSourceAppDatalakeLink(ctx, id, opts...)
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, sourceAppErr := c.GetSourceApp(ctx, link.SourceAppID, opts...)
+	_, datalakeErr := c.GetDatalake(ctx, link.DatalakeID, opts...)
+	if !errors.Is(sourceAppErr, ErrNotFound) && !errors.Is(datalakeErr, ErrNotFound) {
+		if err := errors.Join(sourceAppErr, datalakeErr); err != nil {
+			return false, err
+		}
+		// Both exist again, so the link is no longer dangling
+		return false, nil
+	}
+
+	err = c.DeleteSourceAppDatalakeLink(ctx, id, opts...)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package traceforce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	old := time.Now().Add(-2 * time.Hour)
+	prod := api.addEnvironment(HostingEnvironment{Name: "prod", Status: HostingEnvironmentStatusDisconnected, UpdatedAt: time.Now()})
+	staging := api.addEnvironment(HostingEnvironment{Name: "staging", Status: HostingEnvironmentStatusPending, UpdatedAt: old})
+	lake := api.addDatalake(Datalake{HostingEnvironmentID: prod.ID, Status: DatalakeStatusPending, UpdatedAt: time.Now()})
+	stagingLake := api.addDatalake(Datalake{HostingEnvironmentID: staging.ID, Status: DatalakeStatusPending, UpdatedAt: old})
+	app := api.addSourceApp(SourceApp{HostingEnvironmentID: prod.ID, Status: SourceAppStatusConnected})
+	orphan := api.addSourceApp(SourceApp{HostingEnvironmentID: uuid.NewString()})
+	api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: lake.ID})
+	cross := api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: stagingLake.ID})
+	dangling := api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: uuid.NewString()})
+	ctx := context.Background()
+
+	report, err := client.Audit(ctx, AuditOptions{})
+	assert.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Len(t, report.Findings, 6)
+
+	danglingFindings := report.ByKind(FindingDanglingReference)
+	assert.ElementsMatch(t, []string{orphan.ID, dangling.ID}, []string{danglingFindings[0].ResourceID, danglingFindings[1].ResourceID})
+	if crossFindings := report.ByKind(FindingCrossEnvironmentLink); assert.Len(t, crossFindings, 1) {
+		assert.Equal(t, cross.ID, crossFindings[0].ResourceID)
+	}
+	stuck := report.ByKind(FindingStuckPending)
+	assert.ElementsMatch(t, []string{staging.ID, stagingLake.ID}, []string{stuck[0].ResourceID, stuck[1].ResourceID})
+	if disconnected := report.ByKind(FindingDisconnectedEnvironment); assert.Len(t, disconnected, 1) {
+		assert.Equal(t, prod.ID, disconnected[0].ResourceID)
+	}
+
+	report, err = client.Audit(ctx, AuditOptions{PendingThreshold: 3 * time.Hour, Fix: true})
+	assert.NoError(t, err)
+	assert.Empty(t, report.ByKind(FindingStuckPending))
+	for _, f := range report.Findings {
+		assert.Equal(t, f.ResourceID == dangling.ID, f.Fixed, f.String())
+	}
+	assert.Equal(t, 1, api.count("DELETE", "/source-apps-datalakes/"+dangling.ID))
+	links, _ := client.GetSourceAppDatalakeLinks(ctx)
+	assert.Len(t, links, 2)
+}
+
+func TestAuditFixSkipsRecreatedDatalake(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	env := api.addEnvironment(HostingEnvironment{Name: "prod"})
+	app := api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID})
+	link := api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: uuid.NewString()})
+
+	// The datalake appears between listing and fixing
+	inv, err := client.fetchInventory(context.Background(), nil)
+	assert.NoError(t, err)
+	findings := auditInventory(inv, DefaultPendingThreshold, time.Now())
+	assert.Len(t, findings, 1)
+	api.addDatalake(Datalake{ID: link.DatalakeID, HostingEnvironmentID: env.ID})
+
+	fixed, err := client.deleteDanglingLink(context.Background(), link.ID, nil)
+	assert.NoError(t, err)
+	assert.False(t, fixed)
+	assert.Equal(t, 0, api.count("DELETE", "/source-apps-datalakes/"+link.ID))
+}
@@ -0,0 +1,169 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultBatchConcurrency = 8
+
+// BatchResult holds the outcome of a batch read. Every requested ID is a key
+// of either Items or Errors.
+type BatchResult[T any] struct {
+	Items  map[string]*T
+	Errors map[string]error
+}
+
+// Err returns nil if every ID was fetched, and otherwise an error listing
+// the IDs that failed.
+func (r *BatchResult[T]) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(r.Errors))
+	for id := range r.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	errs := make([]error, 0, len(ids))
+	for _, id := range ids {
+		errs = append(errs, fmt.Errorf("%s: %w", id, r.Errors[id]))
+	}
+	return errors.Join(errs...)
+}
+
+// fetchBatch calls get for every distinct ID, with at most c.batchConcurrency
+// calls in flight.
+func fetchBatch[T any](ctx context.Context, c *Client, ids []string, get func(context.Context, string) (*T, error)) *BatchResult[T] {
+	result := &BatchResult[T]{
+		Items:  make(map[string]*T),
+		Errors: make(map[string]error),
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, c.batchConcurrency)
+	)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			result.Errors[id] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			item, err := get(ctx, id)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Errors[id] = err
+				return
+			}
+			result.Items[id] = item
+		}(id)
+	}
+	wg.Wait()
+
+	return result
+}
+
+type hedgeResult struct {
+	resp  *http.Response
+	err   error
+	index int
+}
+
+// doHedged sends req and, if it is not answered within delay, an identical
+// second request. The first response wins and the other request is
+// canceled. req must not have a body.
+func (c *Client) doHedged(req *http.Request, delay time.Duration) (*http.Response, error) {
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	start := func(r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := c.do(r.WithContext(ctx))
+			results <- hedgeResult{resp: resp, err: err, index: index}
+		}()
+	}
+
+	start(req)
+	inFlight := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			c.logger.DebugContext(req.Context(), "traceforce request is slow, sending hedged request",
+				"path", req.URL.Path, "delay", delay)
+			start(req.Clone(req.Context()))
+			inFlight++
+		case res := <-results:
+			inFlight--
+			// Wait for the hedged request before giving up
+			if res.err != nil && inFlight > 0 {
+				cancels[res.index]()
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != res.index {
+					cancel()
+				}
+			}
+			if inFlight > 0 {
+				go discardHedged(results, inFlight)
+			}
+			if res.err != nil {
+				cancels[res.index]()
+				return nil, res.err
+			}
+			res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancels[res.index]}
+			return res.resp, nil
+		}
+	}
+}
+
+// discardHedged closes the responses of the n requests that lost the race.
+func discardHedged(results <-chan hedgeResult, n int) {
+	for i := 0; i < n; i++ {
+		if res := <-results; res.resp != nil {
+			res.resp.Body.Close()
+		}
+	}
+}
+
+// cancelOnClose releases the context of a request once its response body
+// has been closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
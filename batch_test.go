@@ -0,0 +1,106 @@
+package traceforce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDatalakesByIDs(t *testing.T) {
+	const missingID = "550e8400-e29b-41d4-a716-446655440099"
+
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		id := strings.TrimPrefix(r.URL.Path, "/datalakes/")
+		if id == missingID {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+		w.Write([]byte(`{"id": "` + id + `"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, &ClientOptions{BatchConcurrency: 3})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	ids := []string{missingID, "invalid-uuid"}
+	for i := 0; i < 10; i++ {
+		ids = append(ids, "550e8400-e29b-41d4-a716-44665544000"+string(rune('0'+i)))
+	}
+	ids = append(ids, ids[2])
+
+	result := client.GetDatalakesByIDs(context.Background(), ids)
+	assert.Len(t, result.Items, 10)
+	assert.Len(t, result.Errors, 2)
+	assert.Equal(t, ids[2], result.Items[ids[2]].ID)
+	assert.Contains(t, result.Errors[missingID].Error(), "HTTP 404")
+	assert.Contains(t, result.Errors["invalid-uuid"].Error(), "invalid UUID format")
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+
+	err = result.Err()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), missingID)
+	assert.Contains(t, err.Error(), "invalid-uuid")
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	sourceApps := client.GetSourceAppsByIDs(canceled, ids[2:4])
+	assert.Len(t, sourceApps.Errors, 2)
+	assert.Empty(t, sourceApps.Items)
+}
+
+func TestWithHedge(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request is slow, the hedged one is not
+		if requests.Add(1) == 1 {
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "hedged"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	start := time.Now()
+	datalake, err := client.GetDatalake(context.Background(), "550e8400-e29b-41d4-a716-446655440000", WithHedge(20*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, "hedged", datalake.Name)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// Writes are never hedged
+	requests.Store(1)
+	_, err = client.CreateDatalake(context.Background(), CreateDatalakeRequest{
+		HostingEnvironmentID: "550e8400-e29b-41d4-a716-446655440000",
+		Type:                 DatalakeTypeBigQuery,
+	}, WithHedge(time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+}
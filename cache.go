@@ -0,0 +1,403 @@
+package traceforce
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheEntry is a cached API response body.
+type CacheEntry struct {
+	Body []byte
+	ETag string
+	// Expires is when the entry has to be revalidated with the API. Entries
+	// without a max-age are revalidated on every read.
+	Expires time.Time
+}
+
+// CacheStorage stores cached API responses. Implementations must be safe for
+// concurrent use.
+type CacheStorage interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// LRUCache is an in-memory CacheStorage that evicts the least recently used
+// entry once it holds size entries.
+type LRUCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries *list.List
+	items   map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCache returns an LRUCache holding at most size entries.
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &LRUCache{
+		size:    size,
+		entries: list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+func (l *LRUCache) Get(key string) (*CacheEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.entries.MoveToFront(element)
+	return element.Value.(*lruItem).entry, true
+}
+
+func (l *LRUCache) Set(key string, entry *CacheEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		l.entries.MoveToFront(element)
+		return
+	}
+	l.items[key] = l.entries.PushFront(&lruItem{key: key, entry: entry})
+	if l.entries.Len() > l.size {
+		oldest := l.entries.Back()
+		l.entries.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (l *LRUCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		l.entries.Remove(element)
+		delete(l.items, key)
+	}
+}
+
+// Contains reports whether key is cached, without counting as a use of it.
+func (l *LRUCache) Contains(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.items[key]
+	return ok
+}
+
+// Len returns the number of cached entries.
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.entries.Len()
+}
+
+// CacheStats counts the cacheable reads of a client.
+type CacheStats struct {
+	// Hits were served from the cache without contacting the API.
+	Hits uint64
+	// Revalidations were confirmed unchanged by the API with a 304.
+	Revalidations uint64
+	// Misses downloaded a new response.
+	Misses uint64
+}
+
+// HitRate returns the share of reads that did not download a response.
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Revalidations + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits+s.Revalidations) / float64(total)
+}
+
+// responseCache caches GET responses by API key, request headers and path,
+// revalidating them with If-None-Match once they expire.
+type responseCache struct {
+	storage   CacheStorage
+	telemetry *telemetry
+
+	// keys indexes the stored keys by API key and collection, so writes can
+	// drop every cached variant of a collection, filtered lists included.
+	// Keys the storage evicted are swept out once the index has doubled.
+	mu        sync.Mutex
+	keys      map[string]map[string]bool
+	indexed   int
+	nextSweep int
+
+	hits          atomic.Uint64
+	revalidations atomic.Uint64
+	misses        atomic.Uint64
+}
+
+// minIndexSweep is the index size below which evicted keys are not swept.
+const minIndexSweep = 256
+
+// cachePeeker is implemented by storages that can tell whether a key is
+// stored without counting it as a use, such as LRUCache.
+type cachePeeker interface {
+	Contains(key string) bool
+}
+
+// cacheDependents lists, per collection, the collections whose resources
+// the API deletes along with a resource of it.
+var cacheDependents = map[string][]string{
+	"/hosting-environments": {"/datalakes", "/source-apps", "/source-apps-datalakes"},
+	"/datalakes":            {"/source-apps-datalakes"},
+	"/source-apps":          {"/source-apps-datalakes"},
+}
+
+// cacheKeyIgnoredHeaders are the request headers that do not select a
+// response variant: hop-by-hop headers, those controlling the cache itself
+// and those that change on every request or attempt.
+var cacheKeyIgnoredHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Cache-Control":       true,
+	"If-None-Match":       true,
+	"Authorization":       true,
+	"X-Request-Id":        true,
+	"Idempotency-Key":     true,
+	"Traceparent":         true,
+	"Tracestate":          true,
+	"Baggage":             true,
+}
+
+// key scopes cache entries to the credentials they were fetched with and
+// the other request headers, such as a tenant header, that may select a
+// different response.
+func (rc *responseCache) key(header http.Header, path string) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if !cacheKeyIgnoredHeaders[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	variant := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(variant, "%s: %q\n", http.CanonicalHeaderKey(name), header[name])
+	}
+	return credentialsScope(header) + "." + hex.EncodeToString(variant.Sum(nil)[:8]) + " " + path
+}
+
+// credentialsScope identifies the credentials of a request in cache keys.
+func credentialsScope(header http.Header) string {
+	sum := sha256.Sum256([]byte(header.Get("Authorization")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// splitKey returns the credentials part and the path of a cache key.
+func splitKey(key string) (scope, path string) {
+	scope, path, _ = strings.Cut(key, " ")
+	scope, _, _ = strings.Cut(scope, ".")
+	return scope, path
+}
+
+// collectionOf returns the collection of a path, e.g. "/datalakes" for
+// "/datalakes/123" and "/datalakes?hosting_environment_id=123".
+func collectionOf(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	if i := strings.Index(strings.TrimPrefix(path, "/"), "/"); i >= 0 {
+		return path[:i+1]
+	}
+	return path
+}
+
+func (rc *responseCache) record(ctx context.Context, operation, result string) {
+	switch result {
+	case "hit":
+		rc.hits.Add(1)
+	case "revalidated":
+		rc.revalidations.Add(1)
+	case "miss":
+		rc.misses.Add(1)
+	}
+	rc.telemetry.recordCache(ctx, operation, result)
+}
+
+// lookup returns the cache key of a request and its cached entry, if any.
+// Only GETs are cached. A Cache-Control request header of no-store bypasses
+// the cache and no-cache forces revalidation. A nil cache caches nothing.
+func (rc *responseCache) lookup(method, path string, header http.Header) (key string, entry *CacheEntry, fresh bool) {
+	if rc == nil || method != "GET" {
+		return "", nil, false
+	}
+	requestCacheControl := strings.ToLower(header.Get("Cache-Control"))
+	if strings.Contains(requestCacheControl, "no-store") {
+		return "", nil, false
+	}
+
+	key = rc.key(header, path)
+	entry, ok := rc.storage.Get(key)
+	if !ok {
+		return key, nil, false
+	}
+	fresh = time.Now().Before(entry.Expires) && !strings.Contains(requestCacheControl, "no-cache")
+	return key, entry, fresh
+}
+
+// store caches body according to the Cache-Control and ETag headers of the
+// response.
+func (rc *responseCache) store(key string, body []byte, header http.Header) {
+	maxAge, noStore := parseCacheControl(header.Get("Cache-Control"))
+	etag := header.Get("ETag")
+	if noStore || (etag == "" && maxAge <= 0) {
+		rc.storage.Delete(key)
+		return
+	}
+
+	entry := &CacheEntry{Body: body, ETag: etag}
+	if maxAge > 0 {
+		entry.Expires = time.Now().Add(maxAge)
+	}
+	rc.storage.Set(key, entry)
+
+	scope, path := splitKey(key)
+	index := scope + " " + collectionOf(path)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.keys == nil {
+		rc.keys = make(map[string]map[string]bool)
+	}
+	if rc.keys[index] == nil {
+		rc.keys[index] = make(map[string]bool)
+	}
+	if rc.keys[index][key] {
+		return
+	}
+	rc.keys[index][key] = true
+	rc.indexed++
+	if rc.indexed >= rc.nextSweep {
+		rc.sweep()
+		rc.nextSweep = max(2*rc.indexed, minIndexSweep)
+	}
+}
+
+// sweep drops the keys the storage no longer holds from the index. It must
+// be called with rc.mu held.
+func (rc *responseCache) sweep() {
+	contains := func(key string) bool {
+		_, ok := rc.storage.Get(key)
+		return ok
+	}
+	if peeker, ok := rc.storage.(cachePeeker); ok {
+		contains = peeker.Contains
+	}
+	for index, keys := range rc.keys {
+		for key := range keys {
+			if !contains(key) {
+				delete(keys, key)
+				rc.indexed--
+			}
+		}
+		if len(keys) == 0 {
+			delete(rc.keys, index)
+		}
+	}
+}
+
+// invalidate drops the cached responses of path, of the resources it is
+// nested in, and every list of its collection, with or without query, after
+// a write to it. All header variants fetched with the same credentials are
+// dropped.
+// Deletes also drop the collections of dependent resources, since the API
+// deletes those along with it.
+func (rc *responseCache) invalidate(header http.Header, method, path string) {
+	if rc == nil {
+		return
+	}
+	path, _, _ = strings.Cut(path, "?")
+	collection := collectionOf(path)
+	scope := credentialsScope(header)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	index := scope + " " + collection
+	for key := range rc.keys[index] {
+		_, keyPath := splitKey(key)
+		keyPath, _, _ = strings.Cut(keyPath, "?")
+		if keyPath == collection || keyPath == path || strings.HasPrefix(path, keyPath+"/") {
+			rc.storage.Delete(key)
+			delete(rc.keys[index], key)
+			rc.indexed--
+		}
+	}
+	if len(rc.keys[index]) == 0 {
+		delete(rc.keys, index)
+	}
+	if method != "DELETE" {
+		return
+	}
+	for _, dependent := range cacheDependents[collection] {
+		index := scope + " " + dependent
+		for key := range rc.keys[index] {
+			rc.storage.Delete(key)
+		}
+		rc.indexed -= len(rc.keys[index])
+		delete(rc.keys, index)
+	}
+}
+
+// parseCacheControl returns the max-age of a Cache-Control header and
+// whether it forbids storing the response. no-cache yields a zero max-age.
+func parseCacheControl(value string) (maxAge time.Duration, noStore bool) {
+	noCache := false
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			noStore = true
+		case "no-cache":
+			noCache = true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil && seconds > 0 {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if noCache {
+		maxAge = 0
+	}
+	return maxAge, noStore
+}
+
+// CacheStats returns the cache hit and miss counts of the client. They are
+// zero when no cache is configured.
+func (c *Client) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		Hits:          c.cache.hits.Load(),
+		Revalidations: c.cache.revalidations.Load(),
+		Misses:        c.cache.misses.Load(),
+	}
+}
@@ -0,0 +1,276 @@
+package traceforce
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestResponseCacheETag(t *testing.T) {
+	var downloads, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Write([]byte(`[{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "lake"}]`))
+	}))
+	defer server.Close()
+
+	reader := sdkmetric.NewManualReader()
+	client, err := NewClient("test-key", server.URL, &ClientOptions{
+		Cache:         NewLRUCache(10),
+		MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		datalakes, err := client.GetDatalakes(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, datalakes, 1)
+		assert.Equal(t, "lake", datalakes[0].Name)
+	}
+	assert.Equal(t, int32(1), downloads.Load())
+	assert.Equal(t, int32(2), notModified.Load())
+	assert.Equal(t, CacheStats{Revalidations: 2, Misses: 1}, client.CacheStats())
+	assert.InDelta(t, 2.0/3.0, client.CacheStats().HitRate(), 0.001)
+
+	// Other API keys do not share entries
+	_, err = client.WithAPIKey("other-key").GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), downloads.Load())
+
+	// Writes invalidate the collection
+	err = client.DeleteDatalake(ctx, "550e8400-e29b-41d4-a716-446655440000")
+	assert.NoError(t, err)
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), downloads.Load())
+
+	var metrics metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(ctx, &metrics))
+	counts := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != "traceforce.client.cache" {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				result, _ := point.Attributes.Value(attrCacheResult)
+				counts[result.AsString()] += point.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{"miss": 3, "revalidated": 2}, counts)
+}
+
+func TestResponseCacheMaxAge(t *testing.T) {
+	var requests atomic.Int32
+	cacheControl := "max-age=60"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "env"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, &ClientOptions{Cache: NewLRUCache(10)})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	id := "550e8400-e29b-41d4-a716-446655440000"
+
+	for i := 0; i < 3; i++ {
+		environment, err := client.GetHostingEnvironment(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, "env", environment.Name)
+	}
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, uint64(2), client.CacheStats().Hits)
+
+	// no-cache on the request forces a round trip
+	_, err = client.GetHostingEnvironment(ctx, id, WithHeader("Cache-Control", "no-cache"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// no-store responses are not cached
+	cacheControl = "no-store"
+	client, err = NewClient("test-key", server.URL, &ClientOptions{Cache: NewLRUCache(10)})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err = client.GetHostingEnvironment(ctx, id)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(4), requests.Load())
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2)
+	cache.Set("a", &CacheEntry{ETag: "a"})
+	cache.Set("b", &CacheEntry{ETag: "b"})
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	cache.Set("c", &CacheEntry{ETag: "c"})
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestResponseCacheIndexPruning(t *testing.T) {
+	rc := &responseCache{storage: NewLRUCache(10)}
+	response := http.Header{"Cache-Control": {"max-age=60"}}
+	// Rotated credentials leave their entries behind for the LRU to evict
+	for i := 0; i < 5000; i++ {
+		header := http.Header{"Authorization": {fmt.Sprintf("Bearer key-%d", i)}}
+		rc.store(rc.key(header, "/datalakes"), []byte("[]"), response)
+	}
+	indexed := 0
+	for _, keys := range rc.keys {
+		indexed += len(keys)
+	}
+	assert.Equal(t, rc.indexed, indexed)
+	assert.Less(t, indexed, 2*minIndexSweep)
+	assert.Less(t, len(rc.keys), 2*minIndexSweep)
+
+	// Evicted keys are still swept with storages that cannot peek
+	rc = &responseCache{storage: struct{ CacheStorage }{NewLRUCache(10)}}
+	for i := 0; i < 5000; i++ {
+		header := http.Header{"Authorization": {fmt.Sprintf("Bearer key-%d", i)}}
+		rc.store(rc.key(header, "/datalakes"), []byte("[]"), response)
+	}
+	assert.Less(t, rc.indexed, 2*minIndexSweep)
+}
+
+func TestParseCacheControl(t *testing.T) {
+	maxAge, noStore := parseCacheControl("public, max-age=30")
+	assert.Equal(t, 30*time.Second, maxAge)
+	assert.False(t, noStore)
+
+	maxAge, _ = parseCacheControl("max-age=30, no-cache")
+	assert.Zero(t, maxAge)
+
+	_, noStore = parseCacheControl("no-store")
+	assert.True(t, noStore)
+}
+
+func TestResponseCacheInvalidation(t *testing.T) {
+	api := newFakeAPI(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		api.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client, err := NewClient("test-key", server.URL, &ClientOptions{Cache: NewLRUCache(100)})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	env := api.addEnvironment(HostingEnvironment{Name: "prod"})
+	app := api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID})
+
+	// Filtered lists are dropped by writes to their collection
+	datalakes, err := client.GetDatalakesByHostingEnvironment(ctx, env.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, datalakes)
+	lake, err := client.CreateDatalake(ctx, CreateDatalakeRequest{HostingEnvironmentID: env.ID, Type: DatalakeTypeBigQuery, Name: "lake"})
+	assert.NoError(t, err)
+	datalakes, err = client.GetDatalakesByHostingEnvironment(ctx, env.ID)
+	assert.NoError(t, err)
+	assert.Len(t, datalakes, 1)
+
+	_, err = client.CreateSourceAppDatalakeLink(ctx, CreateSourceAppDatalakeLinkRequest{SourceAppID: app.ID, DatalakeID: lake.ID})
+	assert.NoError(t, err)
+	links, err := client.GetSourceAppDatalakeLinksBySourceApp(ctx, app.ID)
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	_, err = client.GetDatalake(ctx, lake.ID)
+	assert.NoError(t, err)
+
+	// Deleting a hosting environment drops the cached entries of its children
+	assert.NoError(t, client.DeleteHostingEnvironment(ctx, env.ID))
+	api.mu.Lock()
+	delete(api.datalakes, lake.ID)
+	for id := range api.links {
+		delete(api.links, id)
+	}
+	api.mu.Unlock()
+	links, err = client.GetSourceAppDatalakeLinksBySourceApp(ctx, app.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, links)
+	_, err = client.GetDatalake(ctx, lake.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	datalakes, err = client.GetDatalakesByHostingEnvironment(ctx, env.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, datalakes)
+}
+
+func TestResponseCacheHeaderVariants(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(`[{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "` + r.Header.Get("x-tenant") + `"}]`))
+	}))
+	defer server.Close()
+	client, err := NewClient("test-key", server.URL, &ClientOptions{Cache: NewLRUCache(10)})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	acme := client.WithHeaders(map[string]string{"x-tenant": "acme"})
+	globex := client.WithHeaders(map[string]string{"x-tenant": "globex"})
+	for i := 0; i < 2; i++ {
+		environments, err := acme.GetHostingEnvironments(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", environments[0].Name)
+		environments, err = globex.GetHostingEnvironments(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "globex", environments[0].Name)
+	}
+	assert.Equal(t, int32(2), requests.Load())
+
+	// Per-call headers select their own entries too
+	environments, err := client.GetHostingEnvironments(ctx, WithHeader("x-tenant", "initech"))
+	assert.NoError(t, err)
+	assert.Equal(t, "initech", environments[0].Name)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// Headers that change per call do not
+	_, err = acme.GetHostingEnvironments(ctx, WithRequestID("request-1"), WithIdempotencyKey("key-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// Writes drop every variant
+	assert.NoError(t, globex.DeleteHostingEnvironment(ctx, "550e8400-e29b-41d4-a716-446655440000"))
+	_, err = acme.GetHostingEnvironments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), requests.Load())
+}
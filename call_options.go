@@ -0,0 +1,95 @@
+package traceforce
+
+import (
+	"net/http"
+	"time"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// CallOption configures a single API call, on top of the client-wide
+// ClientOptions.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	headers map[string]string
+	timeout time.Duration
+	hedge   time.Duration
+
+	labelSelector LabelSelector
+}
+
+// WithHeader sets a header on the request, overriding any client-wide extra
+// header of the same name.
+func WithHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+		o.headers[key] = value
+	}
+}
+
+// WithTimeout bounds the duration of the call. It applies in addition to any
+// deadline of the context passed to the method.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithIdempotencyKey sets the Idempotency-Key header so that the API can
+// deduplicate retried requests. The Update*WithRetry helpers send key on
+// their first attempt and key-attempt-N on the Nth.
+func WithIdempotencyKey(key string) CallOption {
+	return WithHeader(idempotencyKeyHeader, key)
+}
+
+// WithRequestID sets the request ID sent in the X-Request-ID header instead
+// of a generated one.
+func WithRequestID(id string) CallOption {
+	return WithHeader(requestIDHeader, id)
+}
+
+// WithIfMatch makes an update conditional on the resource still having the
+// given ETag, as returned in the ETag field of a resource. If the resource
+// has changed the call fails with ErrPreconditionFailed.
+func WithIfMatch(etag string) CallOption {
+	return WithHeader("If-Match", etag)
+}
+
+// WithIfUnmodifiedSince makes an update conditional on the resource not
+// having been modified after updatedAt, typically its UpdatedAt field. HTTP
+// dates have a resolution of one second, so prefer WithIfMatch when the
+// resource has an ETag. If the resource has changed the call fails with
+// ErrPreconditionFailed.
+func WithIfUnmodifiedSince(updatedAt time.Time) CallOption {
+	return WithHeader("If-Unmodified-Since", updatedAt.UTC().Format(http.TimeFormat))
+}
+
+// WithHedge sends a second, identical request when a GET has not been
+// answered within delay, and uses whichever response arrives first. It cuts
+// tail latency at the cost of extra load, so delay is best set around the
+// p95 latency. It has no effect on other methods.
+func WithHedge(delay time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.hedge = delay
+	}
+}
+
+// WithLabelSelector restricts list calls to resources whose labels match
+// selector. The selector is sent to the API and also applied to the
+// results.
+func WithLabelSelector(selector LabelSelector) CallOption {
+	return func(o *callOptions) {
+		o.labelSelector = selector
+	}
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,69 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallOptions(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, &ClientOptions{
+		ExtraHeaders: map[string]string{"x-custom-header": "client-value"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.CreateDatalake(context.Background(), CreateDatalakeRequest{Name: "lake"},
+		WithHeader("x-custom-header", "call-value"),
+		WithHeader("x-other-header", "other"),
+		WithIdempotencyKey("idem-123"),
+		WithRequestID("req-456"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "call-value", header.Get("x-custom-header"))
+	assert.Equal(t, "other", header.Get("x-other-header"))
+	assert.Equal(t, "idem-123", header.Get("Idempotency-Key"))
+	assert.Equal(t, "req-456", header.Get("X-Request-ID"))
+	assert.Equal(t, "Bearer test-key", header.Get("Authorization"))
+
+	// Options do not leak into later calls
+	_, err = client.GetDatalakes(context.Background())
+	assert.Error(t, err) // the server returns an object, not a list
+	assert.Equal(t, "client-value", header.Get("x-custom-header"))
+	assert.Empty(t, header.Get("Idempotency-Key"))
+	assert.NotEqual(t, "req-456", header.Get("X-Request-ID"))
+}
+
+func TestCallOptionTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	start := time.Now()
+	_, err = client.GetHostingEnvironments(context.Background(), WithTimeout(20*time.Millisecond))
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,223 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the API while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all requests fast with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen lets a few trial requests through after the cooldown.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerOptions configures the circuit breaker of a client. A
+// request fails if it times out, cannot reach the API or gets a 5xx
+// response; requests canceled by the caller are not counted.
+type CircuitBreakerOptions struct {
+	// FailureRateThreshold opens the circuit once this share of requests in
+	// a window fails. Defaults to 0.5.
+	FailureRateThreshold float64
+	// MinRequests is how many requests a window needs before the failure
+	// rate is considered. Defaults to 10.
+	MinRequests int
+	// Window is the period over which requests are counted. Defaults to one
+	// minute.
+	Window time.Duration
+	// Cooldown is how long the circuit stays open before trial requests are
+	// let through. Defaults to 30 seconds.
+	Cooldown time.Duration
+	// HalfOpenRequests is how many trial requests must succeed to close the
+	// circuit again. Defaults to 1.
+	HalfOpenRequests int
+	// OnStateChange is called after every state change, e.g. for alerting.
+	OnStateChange func(from, to CircuitState)
+}
+
+type circuitBreaker struct {
+	opts CircuitBreakerOptions
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  int
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	trials      int
+	successes   int
+}
+
+func newCircuitBreaker(opts *CircuitBreakerOptions) *circuitBreaker {
+	if opts == nil {
+		return nil
+	}
+	b := &circuitBreaker{opts: *opts, windowStart: time.Now()}
+	if b.opts.FailureRateThreshold <= 0 {
+		b.opts.FailureRateThreshold = 0.5
+	}
+	if b.opts.MinRequests <= 0 {
+		b.opts.MinRequests = 10
+	}
+	if b.opts.Window <= 0 {
+		b.opts.Window = time.Minute
+	}
+	if b.opts.Cooldown <= 0 {
+		b.opts.Cooldown = 30 * time.Second
+	}
+	if b.opts.HalfOpenRequests <= 0 {
+		b.opts.HalfOpenRequests = 1
+	}
+	return b
+}
+
+// allow admits a request or returns ErrCircuitOpen. The returned function
+// must be called with the outcome of an admitted request. A nil breaker
+// admits everything.
+func (b *circuitBreaker) allow() (func(status int, err error), error) {
+	if b == nil {
+		return func(int, error) {}, nil
+	}
+
+	b.mu.Lock()
+	var transition func()
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.opts.Cooldown {
+			b.mu.Unlock()
+			return nil, ErrCircuitOpen
+		}
+		transition = b.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.trials >= b.opts.HalfOpenRequests {
+			b.mu.Unlock()
+			notify(transition)
+			return nil, ErrCircuitOpen
+		}
+		b.trials++
+	case CircuitClosed:
+		if time.Since(b.windowStart) >= b.opts.Window {
+			b.resetWindow()
+		}
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	notify(transition)
+
+	return func(status int, err error) {
+		b.record(generation, status, err)
+	}, nil
+}
+
+func (b *circuitBreaker) record(generation, status int, err error) {
+	if errors.Is(err, context.Canceled) {
+		// A canceled request says nothing about the server, but a trial
+		// must give its slot back or the breaker stays half-open
+		b.mu.Lock()
+		if generation == b.generation && b.state == CircuitHalfOpen && b.trials > 0 {
+			b.trials--
+		}
+		b.mu.Unlock()
+		return
+	}
+	failed := err != nil || status >= http.StatusInternalServerError
+
+	b.mu.Lock()
+	var transition func()
+	// Outcomes of requests admitted before the last state change are stale
+	if generation == b.generation {
+		switch b.state {
+		case CircuitClosed:
+			b.requests++
+			if failed {
+				b.failures++
+			}
+			if b.requests >= b.opts.MinRequests &&
+				float64(b.failures)/float64(b.requests) >= b.opts.FailureRateThreshold {
+				transition = b.setState(CircuitOpen)
+			}
+		case CircuitHalfOpen:
+			if failed {
+				transition = b.setState(CircuitOpen)
+			} else if b.successes++; b.successes >= b.opts.HalfOpenRequests {
+				transition = b.setState(CircuitClosed)
+			}
+		}
+	}
+	b.mu.Unlock()
+	notify(transition)
+}
+
+// setState changes the state and returns the callback to run once the lock
+// is released. b.mu must be held.
+func (b *circuitBreaker) setState(to CircuitState) func() {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.trials = 0
+	b.successes = 0
+	switch to {
+	case CircuitOpen:
+		b.openedAt = time.Now()
+	case CircuitClosed:
+		b.resetWindow()
+	}
+
+	if b.opts.OnStateChange == nil {
+		return nil
+	}
+	return func() { b.opts.OnStateChange(from, to) }
+}
+
+func (b *circuitBreaker) resetWindow() {
+	b.windowStart = time.Now()
+	b.requests = 0
+	b.failures = 0
+}
+
+func (b *circuitBreaker) currentState() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func notify(callback func()) {
+	if callback != nil {
+		callback()
+	}
+}
+
+// CircuitState returns the state of the client's circuit breaker. It is
+// always CircuitClosed when no breaker is configured.
+func (c *Client) CircuitState() CircuitState {
+	return c.breaker.currentState()
+}
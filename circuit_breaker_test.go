@@ -0,0 +1,133 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	endpoint := newTestEndpoint(t, false)
+
+	var transitions []string
+	client, err := NewClient("test-key", endpoint.server.URL, &ClientOptions{
+		CircuitBreaker: &CircuitBreakerOptions{
+			MinRequests: 4,
+			Cooldown:    50 * time.Millisecond,
+			OnStateChange: func(from, to CircuitState) {
+				transitions = append(transitions, from.String()+"->"+to.String())
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		_, err := client.GetDatalakes(ctx)
+		var apiErr *APIError
+		assert.True(t, errors.As(err, &apiErr))
+	}
+	assert.Equal(t, CircuitOpen, client.CircuitState())
+
+	// Open circuits fail fast without contacting the API
+	_, err = client.GetDatalakes(ctx)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(4), endpoint.requests.Load())
+
+	// A failed trial request opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	_, err = client.GetDatalakes(ctx)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, CircuitOpen, client.CircuitState())
+
+	// A successful trial request closes it
+	endpoint.healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitClosed, client.CircuitState())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, transitions)
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerOptions{MinRequests: 4, FailureRateThreshold: 0.5})
+
+	outcomes := []struct {
+		status int
+		err    error
+	}{
+		{200, nil},
+		{404, nil},
+		{0, context.Canceled},
+		{500, nil},
+		{200, nil},
+	}
+	for _, outcome := range outcomes {
+		record, err := b.allow()
+		assert.NoError(t, err)
+		record(outcome.status, outcome.err)
+	}
+	// One failure in four counted requests stays below the threshold
+	assert.Equal(t, CircuitClosed, b.currentState())
+
+	for i := 0; i < 2; i++ {
+		record, _ := b.allow()
+		record(0, context.DeadlineExceeded)
+	}
+	assert.Equal(t, CircuitOpen, b.currentState())
+
+	_, err := b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestCircuitBreakerHalfOpenLimit(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerOptions{MinRequests: 1, Cooldown: time.Millisecond, HalfOpenRequests: 1})
+
+	record, _ := b.allow()
+	record(503, nil)
+	time.Sleep(2 * time.Millisecond)
+
+	trial, err := b.allow()
+	assert.NoError(t, err)
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	trial(200, nil)
+	assert.Equal(t, CircuitClosed, b.currentState())
+
+	var nilBreaker *circuitBreaker
+	_, err = nilBreaker.allow()
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitClosed, nilBreaker.currentState())
+}
+
+func TestCircuitBreakerCanceledTrial(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerOptions{MinRequests: 1, Cooldown: time.Millisecond, HalfOpenRequests: 1})
+
+	record, _ := b.allow()
+	record(503, nil)
+	time.Sleep(2 * time.Millisecond)
+
+	// A canceled trial frees its slot for the next one
+	trial, err := b.allow()
+	assert.NoError(t, err)
+	trial(0, context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, b.currentState())
+
+	trial, err = b.allow()
+	assert.NoError(t, err)
+	trial(200, nil)
+	assert.Equal(t, CircuitClosed, b.currentState())
+}
@@ -1,8 +1,24 @@
 package traceforce
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -12,13 +28,103 @@ const (
 type Client struct {
 	httpClient   *http.Client
 	baseURL      string
-	apiKey       string
 	extraHeaders map[string]string
+	credentials  CredentialsProvider
+
+	region          string
+	strictResidency bool
+	endpoints       *endpointSet
+	breaker         *circuitBreaker
+	cache           *responseCache
+
+	secretResolvers map[string]SecretResolver
+
+	logger    *slog.Logger
+	logBodies bool
+
+	telemetry *telemetry
+
+	debug *debugDumper
+
+	batchConcurrency int
+
+	// expandUnsupported is set once the API ignored an expand parameter
+	expandUnsupported *atomic.Bool
 }
 
 type ClientOptions struct {
 	// ExtraHeaders allows adding additional headers to all API requests.
 	ExtraHeaders map[string]string `json:"extra_headers,omitempty"`
+
+	// Region selects the data-residency region of the API, e.g. RegionUS or
+	// RegionEU. It determines the base URL unless one is passed to NewClient
+	// (or TRACEFORCE_BASE_URL is set for NewClientFromEnv), in which case that
+	// URL is used and a warning is logged. Datalakes created outside of the
+	// region are reported either way.
+	Region string `json:"region,omitempty"`
+
+	// StrictResidency makes creating a datalake outside of Region fail
+	// instead of logging a warning, and rejects a base URL that is the
+	// endpoint of another region.
+	StrictResidency bool `json:"strict_residency,omitempty"`
+
+	// FailoverBaseURLs lists API base URLs to fail over to, in order, when
+	// the primary base URL is unreachable or returns a gateway error. Only
+	// idempotent requests, and those with an idempotency key, fail over. The
+	// healthy endpoint stays preferred until the primary recovers. With a
+	// Region, the endpoints of other regions are rejected.
+	FailoverBaseURLs []string `json:"failover_base_urls,omitempty"`
+
+	// FailbackInterval is how often the primary is re-probed after a
+	// failover. Defaults to 5 minutes.
+	FailbackInterval time.Duration `json:"failback_interval,omitempty"`
+
+	// CircuitBreaker enables a circuit breaker that fails requests fast with
+	// ErrCircuitOpen while the API is failing. Disabled when nil.
+	CircuitBreaker *CircuitBreakerOptions `json:"-"`
+
+	// Credentials supplies the API key for every request. Defaults to
+	// DefaultCredentialsChain with the key passed to NewClient.
+	Credentials CredentialsProvider `json:"-"`
+
+	// SecretResolvers registers resolvers for secret references by scheme,
+	// e.g. "vault". They are added to, and may override, the default env,
+	// file and exec resolvers.
+	SecretResolvers map[string]SecretResolver `json:"-"`
+
+	// Logger receives a debug record for every request with its method, path,
+	// status, latency, attempt number and request ID. Defaults to discarding.
+	Logger *slog.Logger `json:"-"`
+
+	// LogBodies adds request and response bodies to the debug records, with
+	// secret fields redacted.
+	LogBodies bool `json:"log_bodies,omitempty"`
+
+	// TracerProvider enables OpenTelemetry tracing. Every SDK operation gets
+	// a client span named after it, e.g. "traceforce.CreateDatalake".
+	TracerProvider trace.TracerProvider `json:"-"`
+
+	// Propagator injects the trace context into outgoing requests when
+	// tracing is enabled. Defaults to W3C trace context.
+	Propagator propagation.TextMapPropagator `json:"-"`
+
+	// MeterProvider enables OpenTelemetry metrics for request counts,
+	// latencies and error classes, labelled by operation and status.
+	MeterProvider metric.MeterProvider `json:"-"`
+
+	// DebugWriter receives the full wire dump of every request and response.
+	// The Authorization header and secret fields are masked.
+	DebugWriter io.Writer `json:"-"`
+
+	// Cache enables caching of GET responses, e.g. in NewLRUCache. Responses
+	// are revalidated with If-None-Match when they carry an ETag, and served
+	// without contacting the API for their Cache-Control max-age. Writes
+	// through the client invalidate the affected entries.
+	Cache CacheStorage `json:"-"`
+
+	// BatchConcurrency bounds how many requests batch helpers such as
+	// GetDatalakesByIDs send at once. Defaults to 8.
+	BatchConcurrency int `json:"batch_concurrency,omitempty"`
 }
 
 // NewClient creates a new Traceforce client.
@@ -26,14 +132,40 @@ type ClientOptions struct {
 // url is the Traceforce URL.
 // options is the Traceforce client options.
 func NewClient(key, url string, options *ClientOptions) (*Client, error) {
-	if url == "" {
-		url = defaultBaseURL
-	}
-
 	if options == nil {
 		options = &ClientOptions{}
 	}
 
+	// An explicit url wins over the region's endpoint, e.g. to go through a
+	// proxy, but residency checks still apply
+	region := strings.ToLower(options.Region)
+	var regionOverridden bool
+	if region != "" {
+		regionURL, err := RegionBaseURL(region)
+		if err != nil {
+			return nil, err
+		}
+		if url == "" {
+			url = regionURL
+		}
+		other := regionOfBaseURL(url)
+		if other != "" && other != region && options.StrictResidency {
+			return nil, fmt.Errorf("base URL %s is the endpoint of the %s region, not of %s", url, other, region)
+		}
+		regionOverridden = other != region
+
+		// Failing over must not leave the region either
+		for _, failoverURL := range options.FailoverBaseURLs {
+			if other := regionOfBaseURL(failoverURL); other != "" && other != region {
+				return nil, fmt.Errorf("failover URL %s is the endpoint of the %s region, not of %s", failoverURL, other, region)
+			}
+		}
+	}
+
+	if url == "" {
+		url = defaultBaseURL
+	}
+
 	extraHeaders := make(map[string]string)
 
 	// Copy user-provided headers
@@ -41,6 +173,46 @@ func NewClient(key, url string, options *ClientOptions) (*Client, error) {
 		extraHeaders[k] = v
 	}
 
+	secretResolvers := defaultSecretResolvers()
+	for scheme, resolver := range options.SecretResolvers {
+		secretResolvers[scheme] = resolver
+	}
+
+	credentials := options.Credentials
+	if credentials == nil {
+		credentials = DefaultCredentialsChain(key, secretResolvers)
+	}
+
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	if regionOverridden {
+		logger.Warn("base URL overrides the endpoint of the residency region",
+			"base_url", url,
+			"residency_region", region)
+	}
+
+	telemetry, err := newTelemetry(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up telemetry: %v", err)
+	}
+
+	var debug *debugDumper
+	if options.DebugWriter != nil {
+		debug = &debugDumper{w: options.DebugWriter}
+	}
+
+	var cache *responseCache
+	if options.Cache != nil {
+		cache = &responseCache{storage: options.Cache, telemetry: telemetry}
+	}
+
+	batchConcurrency := options.BatchConcurrency
+	if batchConcurrency <= 0 {
+		batchConcurrency = defaultBatchConcurrency
+	}
+
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
 	}
@@ -48,15 +220,69 @@ func NewClient(key, url string, options *ClientOptions) (*Client, error) {
 	return &Client{
 		httpClient:   httpClient,
 		baseURL:      url,
-		apiKey:       key,
 		extraHeaders: extraHeaders,
+		credentials:  newCachedCredentials(credentials),
+
+		region:          region,
+		strictResidency: options.StrictResidency,
+		endpoints:       newEndpointSet(append([]string{url}, options.FailoverBaseURLs...), options.FailbackInterval),
+		breaker:         newCircuitBreaker(options.CircuitBreaker),
+		cache:           cache,
+
+		secretResolvers: secretResolvers,
+
+		logger:    logger,
+		logBodies: options.LogBodies,
+
+		telemetry: telemetry,
+
+		debug: debug,
+
+		batchConcurrency: batchConcurrency,
+
+		expandUnsupported: &atomic.Bool{},
 	}, nil
 }
 
+// WithAPIKey returns a copy of the client that authenticates with key. The
+// copy shares the connection pool and all other settings of c.
+func (c *Client) WithAPIKey(key string) *Client {
+	derived := c.derive()
+	derived.credentials = newCachedCredentials(StaticCredentials{APIKey: key})
+	return derived
+}
+
+// WithHeaders returns a copy of the client whose extra headers are those of c
+// with headers added on top. The copy shares the connection pool of c, and c
+// itself is left unchanged.
+func (c *Client) WithHeaders(headers map[string]string) *Client {
+	derived := c.derive()
+	for k, v := range headers {
+		derived.extraHeaders[k] = v
+	}
+	return derived
+}
+
+// derive returns a shallow copy of c with its own extra headers map. Clients
+// never mutate their maps after construction, so sharing the rest is safe.
+func (c *Client) derive() *Client {
+	derived := *c
+	derived.extraHeaders = make(map[string]string, len(c.extraHeaders))
+	for k, v := range c.extraHeaders {
+		derived.extraHeaders[k] = v
+	}
+	return &derived
+}
+
 // buildHeaders creates a headers map with authorization and any extra headers
-func (c *Client) buildHeaders() map[string]string {
+func (c *Client) buildHeaders(ctx context.Context) (map[string]string, error) {
+	credentials, err := c.credentials.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
 	headers := map[string]string{
-		"Authorization": "Bearer " + c.apiKey,
+		"Authorization": "Bearer " + credentials.APIKey.Reveal(),
 	}
 
 	// Add extra headers
@@ -64,5 +290,214 @@ func (c *Client) buildHeaders() map[string]string {
 		headers[k] = v
 	}
 
-	return headers
+	return headers, nil
+}
+
+// apiRequest describes a single SDK operation against the API.
+type apiRequest struct {
+	// operation names the SDK method, e.g. "CreateDatalake"
+	operation string
+	method    string
+	// path is relative to the base URL and may include a query string
+	path string
+	// body is marshalled to JSON unless nil
+	body interface{}
+	// attrs are added to the operation span
+	attrs []attribute.KeyValue
+	opts  []CallOption
+}
+
+// call sends r and decodes the JSON response into out, unless out is nil.
+func (c *Client) call(ctx context.Context, r apiRequest, out interface{}) (err error) {
+	options := newCallOptions(r.opts)
+
+	ctx, end := c.telemetry.startOperation(ctx, r.operation, r.attrs...)
+	var status int
+	defer func() { end(status, err) }()
+
+	if options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+	}
+
+	if !options.labelSelector.IsZero() && r.method == "GET" {
+		separator := "?"
+		if strings.Contains(r.path, "?") {
+			separator = "&"
+		}
+		r.path += separator + "label_selector=" + url.QueryEscape(options.labelSelector.String())
+	}
+
+	var jsonBody []byte
+	if r.body != nil {
+		jsonBody, err = json.Marshal(r.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	headers, err := c.buildHeaders(ctx)
+	if err != nil {
+		return err
+	}
+	header := make(http.Header)
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	for k, v := range options.headers {
+		header.Set(k, v)
+	}
+	if r.body != nil {
+		header.Set("Content-Type", "application/json")
+	}
+	// Share one request ID across all attempts
+	if header.Get(requestIDHeader) == "" {
+		header.Set(requestIDHeader, uuid.NewString())
+	}
+	c.telemetry.inject(ctx, header)
+
+	cacheKey, cached, fresh := c.cache.lookup(r.method, r.path, header)
+	if fresh {
+		c.cache.record(ctx, r.operation, "hit")
+		status = http.StatusOK
+		return decodeJSON(cached.Body, cached.ETag, out)
+	}
+	if cached != nil && cached.ETag != "" {
+		header.Set("If-None-Match", cached.ETag)
+	}
+
+	record, err := c.breaker.allow()
+	if err != nil {
+		return err
+	}
+	resp, endpoint, err := c.send(ctx, r.method, r.path, jsonBody, header, options.hedge)
+	if resp != nil {
+		record(resp.StatusCode, nil)
+	} else {
+		record(0, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	status = resp.StatusCode
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		c.cache.record(ctx, r.operation, "revalidated")
+		if resp.Header.Get("ETag") == "" {
+			resp.Header.Set("ETag", cached.ETag)
+		}
+		c.cache.store(cacheKey, cached.Body, resp.Header)
+		return decodeJSON(cached.Body, resp.Header.Get("ETag"), out)
+	}
+
+	if err := validateResponse(resp); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && len(c.endpoints.urls) > 1 {
+			apiErr.Endpoint = endpoint
+		}
+		return err
+	}
+
+	if cacheKey != "" {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %v", err)
+		}
+		c.cache.record(ctx, r.operation, "miss")
+		c.cache.store(cacheKey, body, resp.Header)
+		return decodeJSON(body, resp.Header.Get("ETag"), out)
+	}
+	if r.method != "GET" {
+		c.cache.invalidate(header, r.method, r.path)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return err
+		}
+		setETag(out, resp.Header.Get("ETag"))
+	}
+	return nil
+}
+
+// decodeJSON decodes body into out, unless out is nil, and records etag on
+// resources that keep one.
+func decodeJSON(body []byte, etag string, out interface{}) error {
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return err
+	}
+	setETag(out, etag)
+	return nil
+}
+
+// etagSetter is implemented by resources with an ETag field.
+type etagSetter interface {
+	setETag(etag string)
+}
+
+func setETag(out interface{}, etag string) {
+	if s, ok := out.(etagSetter); ok && etag != "" {
+		s.setETag(etag)
+	}
+}
+
+// send sends a request to the preferred endpoint. Idempotent requests fail
+// over to the other endpoints on transport errors and gateway failures. It
+// returns the endpoint that produced the response. GETs are hedged after the
+// hedge delay when it is positive.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, header http.Header, hedge time.Duration) (*http.Response, string, error) {
+	// Requests that cannot fail over go to the preferred endpoint, never to
+	// a primary that is only being probed
+	idempotent := isIdempotent(method, header)
+	endpoints := c.endpoints.order(idempotent)
+	if !idempotent {
+		endpoints = endpoints[:1]
+	}
+
+	for attempt, endpoint := range endpoints {
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+		httpReq, err := http.NewRequestWithContext(withAttempt(ctx, attempt+1), method, endpoint+path, bodyReader)
+		if err != nil {
+			return nil, endpoint, err
+		}
+		httpReq.Header = header.Clone()
+
+		var resp *http.Response
+		if hedge > 0 && method == "GET" {
+			resp, err = c.doHedged(httpReq, hedge)
+		} else {
+			resp, err = c.do(httpReq)
+		}
+		last := attempt == len(endpoints)-1
+		if err == nil && (!isFailoverStatus(resp.StatusCode) || last) {
+			if !isFailoverStatus(resp.StatusCode) {
+				c.endpoints.succeeded(endpoint)
+			}
+			return resp, endpoint, nil
+		}
+		c.endpoints.failed(endpoint)
+		if err != nil && (last || ctx.Err() != nil) {
+			return nil, endpoint, err
+		}
+
+		attrs := []any{"endpoint", endpoint, "next_endpoint", endpoints[attempt+1], "attempt", attempt + 1}
+		if err != nil {
+			attrs = append(attrs, "error", err.Error())
+		} else {
+			attrs = append(attrs, "status", resp.StatusCode)
+			resp.Body.Close()
+		}
+		c.logger.WarnContext(ctx, "traceforce endpoint failed, failing over", attrs...)
+	}
+
+	// Not reached, the last attempt always returns
+	return nil, "", fmt.Errorf("no endpoints configured")
 }
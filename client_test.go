@@ -1,6 +1,9 @@
 package traceforce
 
 import (
+	"context"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -11,8 +14,9 @@ func TestNewClientWithExtraHeaders(t *testing.T) {
 		t.Fatalf("Failed to create client: %v", err)
 	}
 	
-	if client1.apiKey != "test-key" {
-		t.Errorf("Expected apiKey 'test-key', got '%s'", client1.apiKey)
+	credentials, err := client1.credentials.Credentials(context.Background())
+	if err != nil || credentials.APIKey.Reveal() != "test-key" {
+		t.Errorf("Expected apiKey 'test-key', got %v, %v", credentials, err)
 	}
 	
 	if client1.baseURL != "https://example.com" {
@@ -62,7 +66,10 @@ func TestBuildHeaders(t *testing.T) {
 		t.Fatalf("Failed to create client: %v", err)
 	}
 	
-	headers := client.buildHeaders()
+	headers, err := client.buildHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to build headers: %v", err)
+	}
 	
 	// Check authorization header
 	if headers["Authorization"] != "Bearer test-api-key" {
@@ -82,4 +89,78 @@ func TestBuildHeaders(t *testing.T) {
 	if len(headers) != 3 {
 		t.Errorf("Expected 3 headers, got %d", len(headers))
 	}
-}
\ No newline at end of file
+}
+
+func TestDerivedClients(t *testing.T) {
+	options := &ClientOptions{
+		ExtraHeaders: map[string]string{
+			"x-custom-header": "test-value",
+		},
+	}
+
+	client, err := NewClient("test-api-key", "https://example.com", options)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tenant := client.WithAPIKey("tenant-key").WithHeaders(map[string]string{
+		"x-tenant":        "acme",
+		"x-custom-header": "tenant-value",
+	})
+
+	if tenant.httpClient != client.httpClient {
+		t.Errorf("Expected derived client to share the HTTP client")
+	}
+
+	headers, err := tenant.buildHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to build headers: %v", err)
+	}
+	if headers["Authorization"] != "Bearer tenant-key" {
+		t.Errorf("Expected Authorization 'Bearer tenant-key', got '%s'", headers["Authorization"])
+	}
+	if headers["x-tenant"] != "acme" {
+		t.Errorf("Expected tenant header 'acme', got '%s'", headers["x-tenant"])
+	}
+	if headers["x-custom-header"] != "tenant-value" {
+		t.Errorf("Expected custom header 'tenant-value', got '%s'", headers["x-custom-header"])
+	}
+
+	// The original client is unaffected
+	headers, err = client.buildHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to build headers: %v", err)
+	}
+	if headers["Authorization"] != "Bearer test-api-key" {
+		t.Errorf("Expected Authorization 'Bearer test-api-key', got '%s'", headers["Authorization"])
+	}
+	if len(client.extraHeaders) != 1 || client.extraHeaders["x-custom-header"] != "test-value" {
+		t.Errorf("Expected original extra headers to be unchanged, got %v", client.extraHeaders)
+	}
+}
+
+func TestDerivedClientsConcurrent(t *testing.T) {
+	client, err := NewClient("test-api-key", "https://example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			derived := client.WithHeaders(map[string]string{"x-tenant": strconv.Itoa(i)})
+			headers, err := derived.buildHeaders(context.Background())
+			if err != nil || headers["x-tenant"] != strconv.Itoa(i) {
+				t.Errorf("Expected tenant header %d", i)
+			}
+			client.buildHeaders(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	if len(client.extraHeaders) != 0 {
+		t.Errorf("Expected no extra headers on the original client, got %d", len(client.extraHeaders))
+	}
+}
@@ -0,0 +1,132 @@
+package traceforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// maxUpdateAttempts bounds the read-modify-write cycles of the
+// Update*WithRetry helpers.
+const maxUpdateAttempts = 5
+
+// preconditionFor returns the option that makes an update of a resource
+// conditional on its current version, preferring its ETag.
+func preconditionFor(etag string, updatedAt time.Time) CallOption {
+	if etag != "" {
+		return WithIfMatch(etag)
+	}
+	return WithIfUnmodifiedSince(updatedAt)
+}
+
+// cloneable is implemented by resources that can be deep copied.
+type cloneable[T any] interface {
+	*T
+	clone() *T
+}
+
+// updateWithRetry reads a resource, applies mutate to a copy of it and
+// sends the difference as a conditional update. When the resource changed in
+// between, the cycle starts over with the new version.
+//
+// get must bypass caches. update receives the attempt number, starting at
+// zero, and the current and mutated resource, and returns the current one
+// unchanged when there is nothing to update.
+func updateWithRetry[T any, P cloneable[T]](
+	ctx context.Context,
+	get func(ctx context.Context) (*T, error),
+	mutate func(*T) error,
+	update func(ctx context.Context, attempt int, current, updated *T) (*T, error),
+) (*T, error) {
+	var err error
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		var current *T
+		current, err = get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		updated := P(current).clone()
+		if err := mutate(updated); err != nil {
+			return nil, err
+		}
+
+		var result *T
+		result, err = update(ctx, attempt, current, updated)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("resource kept changing after %d attempts: %w", maxUpdateAttempts, err)
+}
+
+// attemptOptions returns the options of a retried update. Each attempt
+// sends a different body, so a caller's idempotency key gets an attempt
+// suffix; reusing it would make the API replay the first, failed attempt.
+func attemptOptions(opts []CallOption, attempt int) []CallOption {
+	key := newCallOptions(opts).headers[idempotencyKeyHeader]
+	if attempt == 0 || key == "" {
+		return opts
+	}
+	return appendOptions(opts, WithIdempotencyKey(fmt.Sprintf("%s-attempt-%d", key, attempt+1)))
+}
+
+// noCache makes a read bypass fresh cache entries, so that read-modify-write
+// cycles start from the current version.
+func noCache() CallOption {
+	return WithHeader("Cache-Control", "no-cache")
+}
+
+// appendOptions returns opts followed by extra without modifying opts.
+func appendOptions(opts []CallOption, extra ...CallOption) []CallOption {
+	return append(append(make([]CallOption, 0, len(opts)+len(extra)), opts...), extra...)
+}
+
+// cloneSettings deep copies settings, so that nested values can be mutated
+// without affecting the original.
+func cloneSettings(settings map[string]interface{}) map[string]interface{} {
+	if settings == nil {
+		return nil
+	}
+	return cloneValue(settings).(map[string]interface{})
+}
+
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			c[k] = cloneValue(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = cloneValue(item)
+		}
+		return c
+	}
+	return value
+}
+
+// settingsEqual reports whether two settings maps are equal, treating nil
+// and empty maps alike. Maps are compared by their JSON encoding, so numbers
+// decoded from YAML as ints equal those decoded from JSON as floats.
+func settingsEqual(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
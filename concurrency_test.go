@@ -0,0 +1,160 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const concurrencyTestID = "550e8400-e29b-41d4-a716-446655440000"
+
+// versionedServer serves a single datalake with ETag versioning. Setting
+// interfere makes the next update lose a race against another writer.
+type versionedServer struct {
+	mu        sync.Mutex
+	datalake  Datalake
+	version   int
+	interfere bool
+	patches   int
+
+	lastRequest     UpdateDatalakeRequest
+	idempotencyKeys []string
+}
+
+func (s *versionedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == "PATCH" {
+		s.patches++
+		s.idempotencyKeys = append(s.idempotencyKeys, r.Header.Get(idempotencyKeyHeader))
+		if s.interfere {
+			s.interfere = false
+			s.datalake.Name = "renamed-by-someone-else"
+			s.version++
+		}
+		if r.Header.Get("If-Match") != fmt.Sprintf(`"%d"`, s.version) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte("etag mismatch"))
+			return
+		}
+		var req UpdateDatalakeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.lastRequest = req
+		if req.Name != nil {
+			s.datalake.Name = *req.Name + fmt.Sprintf("-v%d", s.version+1)
+		}
+		s.version++
+	}
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, s.version))
+	json.NewEncoder(w).Encode(s.datalake)
+}
+
+func TestUpdateWithRetry(t *testing.T) {
+	backend := &versionedServer{datalake: Datalake{ID: concurrencyTestID, Name: "lake"}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, &ClientOptions{Cache: NewLRUCache(10)})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	datalake, err := client.GetDatalake(ctx, concurrencyTestID)
+	assert.NoError(t, err)
+	assert.Equal(t, `"0"`, datalake.ETag)
+
+	backend.interfere = true
+	var seen []string
+	datalake, err = client.UpdateDatalakeWithRetry(ctx, concurrencyTestID, func(d *Datalake) error {
+		seen = append(seen, d.Name)
+		d.Name = "mine"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "mine-v2", datalake.Name)
+	assert.Equal(t, `"2"`, datalake.ETag)
+	assert.Equal(t, []string{"lake", "renamed-by-someone-else"}, seen)
+	assert.Equal(t, 2, backend.patches)
+
+	// Unchanged resources are not written
+	_, err = client.UpdateDatalakeWithRetry(ctx, concurrencyTestID, func(d *Datalake) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 2, backend.patches)
+
+	// Read-only fields cannot be changed
+	_, err = client.UpdateDatalakeWithRetry(ctx, concurrencyTestID, func(d *Datalake) error {
+		d.Region = "eu-west1"
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only the name, labels, annotations and settings of a datalake can be updated")
+}
+
+func TestUpdateWithRetryIdempotencyKey(t *testing.T) {
+	backend := &versionedServer{datalake: Datalake{ID: concurrencyTestID, Name: "lake"}, interfere: true}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.UpdateDatalakeWithRetry(context.Background(), concurrencyTestID, func(d *Datalake) error {
+		d.Name = "mine"
+		return nil
+	}, WithIdempotencyKey("rename-lake"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rename-lake", "rename-lake-attempt-2"}, backend.idempotencyKeys)
+
+	// Without a key none is made up
+	backend.idempotencyKeys = nil
+	backend.interfere = true
+	_, err = client.UpdateDatalakeWithRetry(context.Background(), concurrencyTestID, func(d *Datalake) error {
+		d.Name = "yours"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", ""}, backend.idempotencyKeys)
+}
+
+func TestPreconditionFailed(t *testing.T) {
+	backend := &versionedServer{datalake: Datalake{ID: concurrencyTestID, Name: "lake"}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	name := "stale"
+	_, err = client.UpdateDatalake(context.Background(), concurrencyTestID, UpdateDatalakeRequest{Name: &name}, WithIfMatch(`"7"`))
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusPreconditionFailed, apiErr.StatusCode)
+	assert.NotErrorIs(t, &APIError{StatusCode: http.StatusConflict}, ErrPreconditionFailed)
+}
+
+func TestPreconditionOptions(t *testing.T) {
+	updatedAt := time.Date(2025, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+	options := newCallOptions([]CallOption{WithIfUnmodifiedSince(updatedAt)})
+	assert.Equal(t, "Tue, 04 Mar 2025 04:06:07 GMT", options.headers["If-Unmodified-Since"])
+
+	options = newCallOptions([]CallOption{preconditionFor(`"3"`, updatedAt)})
+	assert.Equal(t, `"3"`, options.headers["If-Match"])
+
+	options = newCallOptions([]CallOption{preconditionFor("", updatedAt)})
+	assert.Contains(t, options.headers, "If-Unmodified-Since")
+}
@@ -0,0 +1,229 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by NewClientFromEnv.
+const (
+	EnvAPIKey       = "TRACEFORCE_API_KEY"
+	EnvBaseURL      = "TRACEFORCE_BASE_URL"
+	EnvRegion       = "TRACEFORCE_REGION"
+	EnvExtraHeaders = "TRACEFORCE_EXTRA_HEADERS"
+	EnvProfile      = "TRACEFORCE_PROFILE"
+	EnvConfigFile   = "TRACEFORCE_CONFIG_FILE"
+)
+
+// ConfigFile is the profile file, by default
+// ~/.config/traceforce/config.yaml:
+//
+//	default_profile: prod
+//	profiles:
+//	  prod:
+//	    base_url: https://api.traceforce.co/api/v1
+//	    api_key: env://TRACEFORCE_PROD_API_KEY
+//	  staging:
+//	    base_url: https://staging.traceforce.co/api/v1
+//	    api_key: file:///run/secrets/traceforce-staging
+//	    headers:
+//	      x-vercel-protection-bypass: token
+type ConfigFile struct {
+	DefaultProfile string              `yaml:"default_profile"`
+	Profiles       map[string]*Profile `yaml:"profiles"`
+}
+
+// Profile is a named set of client settings in the profile file.
+type Profile struct {
+	BaseURL string `yaml:"base_url"`
+	// Region selects a residency region, see ClientOptions.Region.
+	Region string `yaml:"region"`
+	// APIKey is either a literal key or a secret reference such as
+	// "env://NAME", "file:///path" or "exec://command", see SecretResolver.
+	APIKey  string            `yaml:"api_key"`
+	Headers map[string]string `yaml:"headers"`
+	// Exec is a credentials plugin used when there is no api_key.
+	Exec *ExecCredentials `yaml:"exec"`
+}
+
+// DefaultConfigPath returns the path of the profile file, honouring
+// TRACEFORCE_CONFIG_FILE and XDG_CONFIG_HOME.
+func DefaultConfigPath() string {
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "traceforce", "config.yaml")
+}
+
+// LoadConfigFile reads and parses a profile file.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ConfigFile
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return &config, nil
+}
+
+// Profile returns the named profile. An empty name selects the default
+// profile, which is default_profile or else the profile named "default".
+func (f *ConfigFile) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = f.DefaultProfile
+	}
+	if name == "" {
+		name = "default"
+	}
+	profile, ok := f.Profiles[name]
+	if !ok || profile == nil {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	return profile, nil
+}
+
+// loadProfile loads the profile selected by TRACEFORCE_PROFILE. It returns a
+// nil profile without error when no profile was explicitly requested and
+// there is no profile file or default profile.
+func loadProfile() (*Profile, error) {
+	name := os.Getenv(EnvProfile)
+	path := DefaultConfigPath()
+
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && name == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	profile, err := config.Profile(name)
+	if err != nil {
+		if name == "" && config.DefaultProfile == "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%v in %s", err, path)
+	}
+	return profile, nil
+}
+
+// parseHeaderList parses "key1=value1,key2=value2".
+func parseHeaderList(list string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected key=value", pair)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers, nil
+}
+
+// parseSecretValue treats values containing "://" as secret references.
+func parseSecretValue(value string) Secret {
+	if strings.Contains(value, "://") {
+		return SecretRef(value)
+	}
+	return NewSecret(value)
+}
+
+// NewClientFromEnv creates a client configured from the environment and the
+// profile file. options may be nil.
+//
+// Settings are taken from, in order of precedence:
+//
+//  1. ClientOptions.ExtraHeaders and ClientOptions.Region
+//  2. TRACEFORCE_API_KEY, TRACEFORCE_BASE_URL, TRACEFORCE_REGION and
+//     TRACEFORCE_EXTRA_HEADERS ("key1=value1,key2=value2")
+//  3. The profile named by TRACEFORCE_PROFILE, or the default profile, in
+//     the file named by TRACEFORCE_CONFIG_FILE or DefaultConfigPath
+//  4. Built-in defaults
+//
+// Headers are merged across all levels. Credentials come from
+// ClientOptions.Credentials or else DefaultCredentialsChain, so a profile's
+// api_key may be a secret reference or be replaced by an exec plugin.
+func NewClientFromEnv(options *ClientOptions) (*Client, error) {
+	if options == nil {
+		options = &ClientOptions{}
+	}
+
+	profile, err := loadProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile: %v", err)
+	}
+	if profile == nil {
+		profile = &Profile{}
+	}
+
+	// A region set above the profile level overrides the profile's base URL
+	region := options.Region
+	if region == "" {
+		region = os.Getenv(EnvRegion)
+	}
+	baseURL := profile.BaseURL
+	if region != "" {
+		baseURL = ""
+	} else {
+		region = profile.Region
+	}
+	if url := os.Getenv(EnvBaseURL); url != "" {
+		baseURL = url
+	}
+
+	headers := make(map[string]string)
+	for k, v := range profile.Headers {
+		headers[k] = v
+	}
+	if list := os.Getenv(EnvExtraHeaders); list != "" {
+		envHeaders, err := parseHeaderList(list)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvExtraHeaders, err)
+		}
+		for k, v := range envHeaders {
+			headers[k] = v
+		}
+	}
+	for k, v := range options.ExtraHeaders {
+		headers[k] = v
+	}
+
+	merged := *options
+	merged.ExtraHeaders = headers
+	merged.Region = region
+	client, err := NewClient("", baseURL, &merged)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail early rather than on the first request
+	if _, err := client.credentials.Credentials(context.Background()); err != nil {
+		if errors.Is(err, ErrNoCredentials) {
+			return nil, fmt.Errorf("no API key configured, set %s or api_key in a profile", EnvAPIKey)
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
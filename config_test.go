@@ -0,0 +1,139 @@
+package traceforce
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfigFile = `
+default_profile: prod
+profiles:
+  prod:
+    base_url: https://prod.example.com/api/v1
+    api_key: env://TRACEFORCE_TEST_PROD_KEY
+    headers:
+      x-env: prod
+      x-team: data
+  staging:
+    base_url: https://staging.example.com/api/v1
+    api_key: staging-key
+    headers:
+      x-env: staging
+`
+
+func setupConfigEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfigFile), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv(EnvConfigFile, path)
+	t.Setenv(EnvAPIKey, "")
+	t.Setenv(EnvBaseURL, "")
+	t.Setenv(EnvExtraHeaders, "")
+	t.Setenv(EnvProfile, "")
+	t.Setenv("TRACEFORCE_TEST_PROD_KEY", "prod-key")
+}
+
+func assertAPIKey(t *testing.T, client *Client, key string) {
+	t.Helper()
+	headers, err := client.buildHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to build headers: %v", err)
+	}
+	assert.Equal(t, "Bearer "+key, headers["Authorization"])
+}
+
+func TestNewClientFromEnvDefaultProfile(t *testing.T) {
+	setupConfigEnv(t)
+
+	client, err := NewClientFromEnv(nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	assertAPIKey(t, client, "prod-key")
+	assert.Equal(t, "https://prod.example.com/api/v1", client.baseURL)
+	assert.Equal(t, map[string]string{"x-env": "prod", "x-team": "data"}, client.extraHeaders)
+}
+
+func TestNewClientFromEnvSelectedProfile(t *testing.T) {
+	setupConfigEnv(t)
+	t.Setenv(EnvProfile, "staging")
+
+	client, err := NewClientFromEnv(nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	assertAPIKey(t, client, "staging-key")
+	assert.Equal(t, "https://staging.example.com/api/v1", client.baseURL)
+
+	t.Setenv(EnvProfile, "preview")
+	_, err = NewClientFromEnv(nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `profile "preview" not found`)
+}
+
+func TestNewClientFromEnvPrecedence(t *testing.T) {
+	setupConfigEnv(t)
+	t.Setenv(EnvAPIKey, "env-key")
+	t.Setenv(EnvBaseURL, "https://env.example.com/api/v1")
+	t.Setenv(EnvExtraHeaders, "x-env=from-env, x-extra=1")
+
+	client, err := NewClientFromEnv(&ClientOptions{
+		ExtraHeaders: map[string]string{"x-extra": "from-options"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	assertAPIKey(t, client, "env-key")
+	assert.Equal(t, "https://env.example.com/api/v1", client.baseURL)
+	assert.Equal(t, map[string]string{
+		"x-env":   "from-env",
+		"x-team":  "data",
+		"x-extra": "from-options",
+	}, client.extraHeaders)
+}
+
+func TestNewClientFromEnvWithoutConfigFile(t *testing.T) {
+	t.Setenv(EnvConfigFile, filepath.Join(t.TempDir(), "missing.yaml"))
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvBaseURL, "")
+	t.Setenv(EnvExtraHeaders, "")
+
+	t.Setenv(EnvAPIKey, "env-key")
+	client, err := NewClientFromEnv(nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	assertAPIKey(t, client, "env-key")
+	assert.Equal(t, defaultBaseURL, client.baseURL)
+
+	t.Setenv(EnvAPIKey, "")
+	_, err = NewClientFromEnv(nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no API key configured")
+}
+
+func TestParseHeaderList(t *testing.T) {
+	headers, err := parseHeaderList("a=1,b=2=3, c = 4 ,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2=3", "c": "4"}, headers)
+
+	_, err = parseHeaderList("novalue")
+	assert.Error(t, err)
+}
+
+func TestNewClientFromEnvRegion(t *testing.T) {
+	setupConfigEnv(t)
+	t.Setenv(EnvRegion, "eu")
+
+	client, err := NewClientFromEnv(nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	assert.Equal(t, regionBaseURLs[RegionEU], client.baseURL)
+	assert.Equal(t, RegionEU, client.region)
+}
@@ -0,0 +1,250 @@
+package traceforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCredentials is returned by a CredentialsProvider that has no
+// credentials to offer. A credentials chain moves on to its next provider.
+var ErrNoCredentials = errors.New("no credentials found")
+
+// credentialsExpiryWindow is how long before expiry credentials are
+// refreshed.
+const credentialsExpiryWindow = time.Minute
+
+// Credentials authenticate requests to the API.
+type Credentials struct {
+	APIKey Secret
+	// ExpiresAt is zero for credentials that do not expire.
+	ExpiresAt time.Time
+}
+
+// CredentialsProvider supplies credentials for requests. Clients cache the
+// returned credentials until shortly before they expire.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (*Credentials, error)
+}
+
+// CredentialsProviderFunc adapts a function to the CredentialsProvider
+// interface.
+type CredentialsProviderFunc func(ctx context.Context) (*Credentials, error)
+
+func (f CredentialsProviderFunc) Credentials(ctx context.Context) (*Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials provides a fixed API key.
+type StaticCredentials struct {
+	APIKey string
+}
+
+func (p StaticCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	if p.APIKey == "" {
+		return nil, ErrNoCredentials
+	}
+	return &Credentials{APIKey: NewSecret(p.APIKey)}, nil
+}
+
+// EnvCredentials provides the API key in TRACEFORCE_API_KEY.
+type EnvCredentials struct{}
+
+func (EnvCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	key := os.Getenv(EnvAPIKey)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	return &Credentials{APIKey: NewSecret(key)}, nil
+}
+
+// ProfileCredentials provides the api_key of the profile selected by
+// TRACEFORCE_PROFILE, see NewClientFromEnv. Secret references are resolved
+// with Resolvers, which default to the env, file and exec resolvers.
+type ProfileCredentials struct {
+	Resolvers map[string]SecretResolver
+}
+
+func (p ProfileCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	profile, err := loadProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile: %v", err)
+	}
+	if profile == nil || profile.APIKey == "" {
+		return nil, ErrNoCredentials
+	}
+
+	resolvers := p.Resolvers
+	if resolvers == nil {
+		resolvers = defaultSecretResolvers()
+	}
+	key, err := resolveSecretWith(ctx, resolvers, parseSecretValue(profile.APIKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve profile API key: %v", err)
+	}
+	return &Credentials{APIKey: key}, nil
+}
+
+// ExecCredentials runs a credentials plugin. The command must print a JSON
+// object {"api_key": "...", "expires_at": "<RFC 3339 time>"} to standard
+// output; expires_at is optional.
+type ExecCredentials struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// Env is added to the environment of the command, as "KEY=value".
+	Env []string `yaml:"env"`
+	// Timeout bounds the command run time. Defaults to 30 seconds.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (p ExecCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	if p.Command == "" {
+		return nil, fmt.Errorf("credentials command cannot be empty")
+	}
+
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Env = append(os.Environ(), p.Env...)
+	cmd.Stdout = &stdout
+	// stderr is captured rather than passed through, it may echo the key
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if snippet := stderrSnippet(stderr.Bytes()); snippet != "" {
+			return nil, fmt.Errorf("credentials command %s failed: %v: %s", p.Command, err, snippet)
+		}
+		return nil, fmt.Errorf("credentials command %s failed: %v", p.Command, err)
+	}
+
+	var output struct {
+		APIKey    string    `json:"api_key"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("invalid output from credentials command %s: %v", p.Command, err)
+	}
+	if output.APIKey == "" {
+		return nil, fmt.Errorf("credentials command %s returned no api_key", p.Command)
+	}
+	return &Credentials{APIKey: NewSecret(output.APIKey), ExpiresAt: output.ExpiresAt}, nil
+}
+
+// maxStderrSnippet bounds how much of a plugin's stderr goes into errors.
+const maxStderrSnippet = 200
+
+var (
+	// assignedSecret matches "key=value" style credentials such as
+	// api_key=abc or "token": "abc".
+	assignedSecret = regexp.MustCompile(`(?i)((?:key|token|secret|password|credential)[^\s=:]*"?\s*[=:]\s*)"?[^\s",}]+"?`)
+	// longToken matches tokens long enough to be keys.
+	longToken = regexp.MustCompile(`[A-Za-z0-9_\-.+/=]{16,}`)
+)
+
+// stderrSnippet returns the start of a plugin's stderr on one line, with
+// anything that looks like a credential redacted.
+func stderrSnippet(stderr []byte) string {
+	snippet := strings.Join(strings.Fields(string(stderr)), " ")
+	snippet = assignedSecret.ReplaceAllString(snippet, "${1}"+redactedSecret)
+	snippet = longToken.ReplaceAllString(snippet, redactedSecret)
+	if len(snippet) > maxStderrSnippet {
+		snippet = snippet[:maxStderrSnippet] + "..."
+	}
+	return snippet
+}
+
+// profileExecCredentials runs the exec plugin of the selected profile.
+type profileExecCredentials struct{}
+
+func (profileExecCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	profile, err := loadProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile: %v", err)
+	}
+	if profile == nil || profile.Exec == nil {
+		return nil, ErrNoCredentials
+	}
+	return profile.Exec.Credentials(ctx)
+}
+
+// CredentialsChain tries each provider in order and returns the first
+// credentials found. Providers returning ErrNoCredentials are skipped, any
+// other error ends the search.
+type CredentialsChain []CredentialsProvider
+
+func (chain CredentialsChain) Credentials(ctx context.Context) (*Credentials, error) {
+	for _, provider := range chain {
+		credentials, err := provider.Credentials(ctx)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return credentials, nil
+	}
+	return nil, ErrNoCredentials
+}
+
+// DefaultCredentialsChain returns the chain used when ClientOptions has no
+// Credentials: the explicit key, if any, then TRACEFORCE_API_KEY, then the
+// api_key of the selected profile, then the exec plugin of that profile.
+func DefaultCredentialsChain(key string, resolvers map[string]SecretResolver) CredentialsChain {
+	var chain CredentialsChain
+	if key != "" {
+		chain = append(chain, StaticCredentials{APIKey: key})
+	}
+	return append(chain,
+		EnvCredentials{},
+		ProfileCredentials{Resolvers: resolvers},
+		profileExecCredentials{},
+	)
+}
+
+// cachedCredentials caches the credentials of a provider until shortly
+// before they expire.
+type cachedCredentials struct {
+	provider CredentialsProvider
+
+	mu          sync.Mutex
+	credentials *Credentials
+}
+
+func newCachedCredentials(provider CredentialsProvider) *cachedCredentials {
+	return &cachedCredentials{provider: provider}
+}
+
+func (c *cachedCredentials) Credentials(ctx context.Context) (*Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.credentials != nil {
+		expiresAt := c.credentials.ExpiresAt
+		if expiresAt.IsZero() || time.Now().Before(expiresAt.Add(-credentialsExpiryWindow)) {
+			return c.credentials, nil
+		}
+	}
+
+	credentials, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil || credentials.APIKey.IsZero() {
+		return nil, ErrNoCredentials
+	}
+	c.credentials = credentials
+	return credentials, nil
+}
@@ -0,0 +1,147 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCredentialsPlugin(t *testing.T, output string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plugin.sh")
+	script := fmt.Sprintf("#!/bin/sh\necho run >> %s\ncat <<'EOF'\n%s\nEOF\n", filepath.Join(dir, "runs"), output)
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	return path
+}
+
+func pluginRuns(t *testing.T, plugin string) int {
+	data, _ := os.ReadFile(filepath.Join(filepath.Dir(plugin), "runs"))
+	return len(data) / len("run\n")
+}
+
+func TestDefaultCredentialsChain(t *testing.T) {
+	plugin := writeCredentialsPlugin(t, `{"api_key": "exec-key"}`)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	config := fmt.Sprintf("profiles:\n  default:\n    api_key: profile-key\n  ci:\n    exec:\n      command: %s\n", plugin)
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv(EnvConfigFile, configPath)
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvAPIKey, "env-key")
+	ctx := context.Background()
+
+	credentials, err := DefaultCredentialsChain("explicit-key", nil).Credentials(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "explicit-key", credentials.APIKey.Reveal())
+
+	credentials, err = DefaultCredentialsChain("", nil).Credentials(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "env-key", credentials.APIKey.Reveal())
+
+	t.Setenv(EnvAPIKey, "")
+	credentials, err = DefaultCredentialsChain("", nil).Credentials(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "profile-key", credentials.APIKey.Reveal())
+
+	t.Setenv(EnvProfile, "ci")
+	credentials, err = DefaultCredentialsChain("", nil).Credentials(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "exec-key", credentials.APIKey.Reveal())
+
+	t.Setenv(EnvConfigFile, filepath.Join(t.TempDir(), "missing.yaml"))
+	t.Setenv(EnvProfile, "")
+	_, err = DefaultCredentialsChain("", nil).Credentials(ctx)
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+}
+
+func TestExecCredentials(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	plugin := writeCredentialsPlugin(t, fmt.Sprintf(`{"api_key": "short-lived", "expires_at": %q}`, expiresAt.Format(time.RFC3339)))
+
+	credentials, err := ExecCredentials{Command: plugin}.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "short-lived", credentials.APIKey.Reveal())
+	assert.True(t, expiresAt.Equal(credentials.ExpiresAt))
+
+	bad := writeCredentialsPlugin(t, `not json`)
+	_, err = ExecCredentials{Command: bad}.Credentials(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid output")
+}
+
+func TestCachedCredentials(t *testing.T) {
+	valid := writeCredentialsPlugin(t, fmt.Sprintf(`{"api_key": "key", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339)))
+	cached := newCachedCredentials(ExecCredentials{Command: valid})
+	for i := 0; i < 3; i++ {
+		_, err := cached.Credentials(context.Background())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, pluginRuns(t, valid))
+
+	// Credentials inside the expiry window are refreshed
+	expiring := writeCredentialsPlugin(t, fmt.Sprintf(`{"api_key": "key", "expires_at": %q}`, time.Now().Add(30*time.Second).Format(time.RFC3339)))
+	cached = newCachedCredentials(ExecCredentials{Command: expiring})
+	for i := 0; i < 3; i++ {
+		_, err := cached.Credentials(context.Background())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, pluginRuns(t, expiring))
+}
+
+func TestClientWithCredentialsProvider(t *testing.T) {
+	calls := 0
+	client, err := NewClient("", "https://example.com", &ClientOptions{
+		Credentials: CredentialsProviderFunc(func(ctx context.Context) (*Credentials, error) {
+			calls++
+			return &Credentials{APIKey: NewSecret("broker-key")}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		headers, err := client.buildHeaders(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer broker-key", headers["Authorization"])
+	}
+	assert.Equal(t, 1, calls)
+
+	failing, err := NewClient("", "https://example.com", &ClientOptions{
+		Credentials: CredentialsChain{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	_, err = failing.GetDatalakes(context.Background())
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+}
+
+func TestExecCredentialsStderr(t *testing.T) {
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "plugin.sh")
+	script := "#!/bin/sh\necho 'refreshing api_key=sk-live-abc and token sk_live_0123456789abcdefghij' >&2\nexit 3\n"
+	if err := os.WriteFile(plugin, []byte(script), 0o700); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+
+	_, err := ExecCredentials{Command: plugin}.Credentials(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 3: refreshing api_key=[REDACTED] and token [REDACTED]")
+	assert.NotContains(t, err.Error(), "sk-live-abc")
+	assert.NotContains(t, err.Error(), "sk_live_0123456789")
+
+	long := stderrSnippet([]byte(strings.Repeat("error ", 100)))
+	assert.Len(t, long, maxStderrSnippet+len("..."))
+}
@@ -1,23 +1,23 @@
 package traceforce
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"net/url"
+	"reflect"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type DatalakeStatus string
 
 const (
-	DatalakeStatusPending             DatalakeStatus = "pending"
-	DatalakeStatusDeployed            DatalakeStatus = "deployed"
-	DatalakeStatusReady               DatalakeStatus = "ready"
-	DatalakeStatusFailed              DatalakeStatus = "failed"
+	DatalakeStatusPending  DatalakeStatus = "pending"
+	DatalakeStatusDeployed DatalakeStatus = "deployed"
+	DatalakeStatusReady    DatalakeStatus = "ready"
+	DatalakeStatusFailed   DatalakeStatus = "failed"
 )
 
 type DatalakeType string
@@ -28,58 +28,83 @@ const (
 
 // Request types
 type CreateDatalakeRequest struct {
-	HostingEnvironmentID string       `json:"hosting_environment_id"`
-	Type                 DatalakeType `json:"type"`
-	Name                 string       `json:"name"`
-	EnvironmentNativeID  string       `json:"environment_native_id"`
-	Region               string       `json:"region"`
+	HostingEnvironmentID string                 `json:"hosting_environment_id"`
+	Type                 DatalakeType           `json:"type"`
+	Name                 string                 `json:"name"`
+	EnvironmentNativeID  string                 `json:"environment_native_id"`
+	Region               string                 `json:"region"`
+	Labels               map[string]string      `json:"labels,omitempty"`
+	Annotations          map[string]string      `json:"annotations,omitempty"`
+	Settings             map[string]interface{} `json:"settings,omitempty"`
 }
 
+// UpdateDatalakeRequest changes the fields that are set and leaves the others
+// unchanged. Optional fields are cleared when set to Null.
 type UpdateDatalakeRequest struct {
-	Name *string `json:"name,omitempty"`
+	Name        *string                          `json:"name,omitempty"`
+	Labels      Optional[map[string]string]      `json:"labels,omitzero"`
+	Annotations Optional[map[string]string]      `json:"annotations,omitzero"`
+	Settings    Optional[map[string]interface{}] `json:"settings,omitzero"`
 }
 
 // Response type
 type Datalake struct {
-	ID                   string         `json:"id"`
-	HostingEnvironmentID string         `json:"hosting_environment_id"`
-	Type                 DatalakeType   `json:"type"`
-	Name                 string         `json:"name"`
-	Status               DatalakeStatus `json:"status"`
-	EnvironmentNativeID  string         `json:"environment_native_id"`
-	Region               string         `json:"region"`
-	CreatedAt            time.Time      `json:"created_at"`
-	UpdatedAt            time.Time      `json:"updated_at"`
+	ID                   string                 `json:"id"`
+	HostingEnvironmentID string                 `json:"hosting_environment_id"`
+	Type                 DatalakeType           `json:"type"`
+	Name                 string                 `json:"name"`
+	Status               DatalakeStatus         `json:"status"`
+	EnvironmentNativeID  string                 `json:"environment_native_id"`
+	Region               string                 `json:"region"`
+	Labels               map[string]string      `json:"labels,omitempty"`
+	Annotations          map[string]string      `json:"annotations,omitempty"`
+	Settings             map[string]interface{} `json:"settings,omitempty"`
+	CreatedAt            time.Time              `json:"created_at"`
+	UpdatedAt            time.Time              `json:"updated_at"`
+
+	// ETag identifies the version of the resource for WithIfMatch. It is
+	// only set on resources returned individually.
+	ETag string `json:"-"`
 }
 
-func (c *Client) CreateDatalake(req CreateDatalakeRequest) (*Datalake, error) {
-	url := c.baseURL + "/datalakes"
-	headers := c.buildHeaders()
+func (d *Datalake) setETag(etag string) {
+	d.ETag = etag
+}
 
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
-	}
+func (d Datalake) labelSet() map[string]string {
+	return d.Labels
+}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { httpReq.Header.Set(k, v) }
-	httpReq.Header.Set("Content-Type", "application/json")
+func (d Datalake) resourceID() string {
+	return d.ID
+}
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
+func (d *Datalake) clone() *Datalake {
+	c := *d
+	c.Labels = cloneMap(d.Labels)
+	c.Annotations = cloneMap(d.Annotations)
+	c.Settings = cloneSettings(d.Settings)
+	return &c
+}
+
+func (c *Client) CreateDatalake(ctx context.Context, req CreateDatalakeRequest, opts ...CallOption) (*Datalake, error) {
+	if err := c.checkDatalakeResidency(req.Region); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if err := validateResponse(resp); err != nil {
+	if err := validateLabelsAndAnnotations(req.Labels, req.Annotations); err != nil {
 		return nil, err
 	}
 
 	var createdDatalake Datalake
-	err = json.NewDecoder(resp.Body).Decode(&createdDatalake)
+	err := c.call(ctx, apiRequest{
+		operation: "CreateDatalake",
+		method:    "POST",
+		path:      "/datalakes",
+		body:      req,
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(req.HostingEnvironmentID)},
+		opts:      opts,
+	}, &createdDatalake)
 	if err != nil {
 		return nil, err
 	}
@@ -87,35 +112,22 @@ func (c *Client) CreateDatalake(req CreateDatalakeRequest) (*Datalake, error) {
 	return &createdDatalake, nil
 }
 
-func (c *Client) GetDatalakes() ([]Datalake, error) {
-	url := c.baseURL + "/datalakes"
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
+func (c *Client) GetDatalakes(ctx context.Context, opts ...CallOption) ([]Datalake, error) {
 	var datalakes []Datalake
-	err = json.NewDecoder(resp.Body).Decode(&datalakes)
+	err := c.call(ctx, apiRequest{
+		operation: "GetDatalakes",
+		method:    "GET",
+		path:      "/datalakes",
+		opts:      opts,
+	}, &datalakes)
 	if err != nil {
 		return nil, err
 	}
 
-	return datalakes, nil
+	return filterByLabels(datalakes, newCallOptions(opts).labelSelector), nil
 }
 
-func (c *Client) GetDatalakesByHostingEnvironment(hostingEnvironmentID string) ([]Datalake, error) {
+func (c *Client) GetDatalakesByHostingEnvironment(ctx context.Context, hostingEnvironmentID string, opts ...CallOption) ([]Datalake, error) {
 	if hostingEnvironmentID == "" {
 		return nil, fmt.Errorf("hosting environment ID cannot be empty")
 	}
@@ -126,34 +138,22 @@ func (c *Client) GetDatalakesByHostingEnvironment(hostingEnvironmentID string) (
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/datalakes?hosting_environment_id=" + url.QueryEscape(hostingEnvironmentID)
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var datalakes []Datalake
-	err = json.NewDecoder(resp.Body).Decode(&datalakes)
+	err = c.call(ctx, apiRequest{
+		operation: "GetDatalakesByHostingEnvironment",
+		method:    "GET",
+		path:      "/datalakes?hosting_environment_id=" + url.QueryEscape(hostingEnvironmentID),
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(hostingEnvironmentID)},
+		opts:      opts,
+	}, &datalakes)
 	if err != nil {
 		return nil, err
 	}
 
-	return datalakes, nil
+	return filterByLabels(datalakes, newCallOptions(opts).labelSelector), nil
 }
 
-func (c *Client) GetDatalake(id string) (*Datalake, error) {
+func (c *Client) GetDatalake(ctx context.Context, id string, opts ...CallOption) (*Datalake, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id cannot be empty")
 	}
@@ -164,26 +164,14 @@ func (c *Client) GetDatalake(id string) (*Datalake, error) {
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/datalakes/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var datalake Datalake
-	err = json.NewDecoder(resp.Body).Decode(&datalake)
+	err = c.call(ctx, apiRequest{
+		operation: "GetDatalake",
+		method:    "GET",
+		path:      "/datalakes/" + id,
+		attrs:     []attribute.KeyValue{attrDatalakeID.String(id)},
+		opts:      opts,
+	}, &datalake)
 	if err != nil {
 		return nil, err
 	}
@@ -191,7 +179,17 @@ func (c *Client) GetDatalake(id string) (*Datalake, error) {
 	return &datalake, nil
 }
 
-func (c *Client) UpdateDatalake(id string, req UpdateDatalakeRequest) (*Datalake, error) {
+// GetDatalakesByIDs fetches the datalakes with the given IDs concurrently,
+// with at most ClientOptions.BatchConcurrency requests in flight. IDs that
+// could not be fetched are reported in the result's Errors instead of failing
+// the batch.
+func (c *Client) GetDatalakesByIDs(ctx context.Context, ids []string, opts ...CallOption) *BatchResult[Datalake] {
+	return fetchBatch(ctx, c, ids, func(ctx context.Context, id string) (*Datalake, error) {
+		return c.GetDatalake(ctx, id, opts...)
+	})
+}
+
+func (c *Client) UpdateDatalake(ctx context.Context, id string, req UpdateDatalakeRequest, opts ...CallOption) (*Datalake, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id cannot be empty")
 	}
@@ -202,41 +200,84 @@ func (c *Client) UpdateDatalake(id string, req UpdateDatalakeRequest) (*Datalake
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/datalakes/" + id
-	headers := c.buildHeaders()
-
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
+	labels, _ := req.Labels.Get()
+	annotations, _ := req.Annotations.Get()
+	if err := validateLabelsAndAnnotations(labels, annotations); err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonBody))
+	var updatedDatalake Datalake
+	err = c.call(ctx, apiRequest{
+		operation: "UpdateDatalake",
+		method:    "PATCH",
+		path:      "/datalakes/" + id,
+		body:      req,
+		attrs:     []attribute.KeyValue{attrDatalakeID.String(id)},
+		opts:      opts,
+	}, &updatedDatalake)
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range headers { httpReq.Header.Set(k, v) }
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return &updatedDatalake, nil
+}
 
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
+// UpdateDatalakeWithRetry reads the datalake, applies mutate to it and saves the
+// changes, conditional on the datalake not having changed in between. If it
+// has, the cycle starts over with the new version, up to a few times, and an
+// idempotency key set with WithIdempotencyKey gets an attempt suffix. Only
+// fields accepted by UpdateDatalake may be changed.
+func (c *Client) UpdateDatalakeWithRetry(ctx context.Context, id string, mutate func(*Datalake) error, opts ...CallOption) (*Datalake, error) {
+	return updateWithRetry(ctx,
+		func(ctx context.Context) (*Datalake, error) {
+			return c.GetDatalake(ctx, id, appendOptions(opts, noCache())...)
+		},
+		mutate,
+		func(ctx context.Context, attempt int, current, updated *Datalake) (*Datalake, error) {
+			req, changed, err := datalakeUpdate(current, updated)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				return current, nil
+			}
+			return c.UpdateDatalake(ctx, id, req, appendOptions(attemptOptions(opts, attempt), preconditionFor(current.ETag, current.UpdatedAt))...)
+		})
+}
 
-	var updatedDatalake Datalake
-	err = json.NewDecoder(resp.Body).Decode(&updatedDatalake)
-	if err != nil {
-		return nil, err
+// datalakeUpdate returns the request that turns current into updated.
+func datalakeUpdate(current, updated *Datalake) (UpdateDatalakeRequest, bool, error) {
+	var req UpdateDatalakeRequest
+	changed := false
+	if updated.Name != current.Name {
+		req.Name = &updated.Name
+		changed = true
+	}
+	if !mapsEqual(updated.Labels, current.Labels) {
+		req.Labels = optionalMap(updated.Labels)
+		changed = true
+	}
+	if !mapsEqual(updated.Annotations, current.Annotations) {
+		req.Annotations = optionalMap(updated.Annotations)
+		changed = true
+	}
+	if !settingsEqual(updated.Settings, current.Settings) {
+		req.Settings = optionalMap(updated.Settings)
+		changed = true
 	}
 
-	return &updatedDatalake, nil
+	rest := *updated
+	rest.Name = current.Name
+	rest.Labels = current.Labels
+	rest.Annotations = current.Annotations
+	rest.Settings = current.Settings
+	if !reflect.DeepEqual(&rest, current) {
+		return req, false, fmt.Errorf("only the name, labels, annotations and settings of a datalake can be updated")
+	}
+	return req, changed, nil
 }
 
-func (c *Client) DeleteDatalake(id string) error {
+func (c *Client) DeleteDatalake(ctx context.Context, id string, opts ...CallOption) error {
 	if id == "" {
 		return fmt.Errorf("id cannot be empty")
 	}
@@ -247,23 +288,11 @@ func (c *Client) DeleteDatalake(id string) error {
 		return fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/datalakes/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return err
-	}
-
-	return nil
-}
\ No newline at end of file
+	return c.call(ctx, apiRequest{
+		operation: "DeleteDatalake",
+		method:    "DELETE",
+		path:      "/datalakes/" + id,
+		attrs:     []attribute.KeyValue{attrDatalakeID.String(id)},
+		opts:      opts,
+	}, nil)
+}
@@ -1,6 +1,7 @@
 package traceforce
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -12,6 +13,7 @@ func TestDatalakes(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	// First create a hosting environment for the datalake
 	environmentReq := CreateHostingEnvironmentRequest{
@@ -21,12 +23,12 @@ func TestDatalakes(t *testing.T) {
 		NativeID:      "123456789012",
 	}
 
-	createdEnvironment, err := client.CreateHostingEnvironment(environmentReq)
+	createdEnvironment, err := client.CreateHostingEnvironment(ctx, environmentReq)
 	if err != nil {
 		t.Fatalf("Failed to create hosting environment: %v", err)
 	}
 	defer func() {
-		err := client.DeleteHostingEnvironment(createdEnvironment.ID)
+		err := client.DeleteHostingEnvironment(ctx, createdEnvironment.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup hosting environment: %v", err)
 		}
@@ -39,7 +41,7 @@ func TestDatalakes(t *testing.T) {
 		Name:                 testDatalakeName,
 	}
 
-	createdDatalake, err := client.CreateDatalake(datalakeReq)
+	createdDatalake, err := client.CreateDatalake(ctx, datalakeReq)
 	if err != nil {
 		t.Fatalf("Failed to create datalake: %v", err)
 	}
@@ -51,7 +53,7 @@ func TestDatalakes(t *testing.T) {
 	assert.Equal(t, datalakeReq.HostingEnvironmentID, createdDatalake.HostingEnvironmentID)
 	assert.Equal(t, DatalakeStatusPending, createdDatalake.Status)
 
-	datalakes, err := client.GetDatalakes()
+	datalakes, err := client.GetDatalakes(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get datalakes: %v", err)
 	}
@@ -77,7 +79,7 @@ func TestDatalakes(t *testing.T) {
 	t.Logf("Test datalake: %+v", testDatalake)
 	assert.NotNil(t, testDatalake)
 
-	datalakeByID, err := client.GetDatalake(testDatalake.ID)
+	datalakeByID, err := client.GetDatalake(ctx, testDatalake.ID)
 	if err != nil {
 		t.Fatalf("Failed to get datalake by ID: %v", err)
 	}
@@ -86,7 +88,7 @@ func TestDatalakes(t *testing.T) {
 	assert.Equal(t, testDatalake.ID, datalakeByID.ID)
 	assert.Equal(t, testDatalake.Name, datalakeByID.Name)
 
-	datalakesByEnvironment, err := client.GetDatalakesByHostingEnvironment(createdEnvironment.ID)
+	datalakesByEnvironment, err := client.GetDatalakesByHostingEnvironment(ctx, createdEnvironment.ID)
 	if err != nil {
 		t.Fatalf("Failed to get datalakes by hosting environment: %v", err)
 	}
@@ -107,7 +109,7 @@ func TestDatalakes(t *testing.T) {
 	updateReq := UpdateDatalakeRequest{
 		Name: &newName,
 	}
-	updatedDatalake, err := client.UpdateDatalake(testDatalake.ID, updateReq)
+	updatedDatalake, err := client.UpdateDatalake(ctx, testDatalake.ID, updateReq)
 	if err != nil {
 		t.Fatalf("Failed to update datalake: %v", err)
 	}
@@ -117,7 +119,7 @@ func TestDatalakes(t *testing.T) {
 	assert.Equal(t, newName, updatedDatalake.Name)
 	// Note: Status update is not supported via UpdateDatalakeRequest
 
-	err = client.DeleteDatalake(testDatalake.ID)
+	err = client.DeleteDatalake(ctx, testDatalake.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete datalake: %v", err)
 	}
@@ -130,46 +132,47 @@ func TestDatalakeValidation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	// Test GetDatalakesByHostingEnvironment with empty ID
-	_, err = client.GetDatalakesByHostingEnvironment("")
+	_, err = client.GetDatalakesByHostingEnvironment(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "hosting environment ID cannot be empty")
 
 	// Test GetDatalakesByHostingEnvironment with invalid UUID
-	_, err = client.GetDatalakesByHostingEnvironment("invalid-uuid")
+	_, err = client.GetDatalakesByHostingEnvironment(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test GetDatalake with empty ID
-	_, err = client.GetDatalake("")
+	_, err = client.GetDatalake(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test GetDatalake with invalid UUID
-	_, err = client.GetDatalake("invalid-uuid")
+	_, err = client.GetDatalake(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test UpdateDatalake with empty ID
 	testName := "test"
 	updateReq := UpdateDatalakeRequest{Name: &testName}
-	_, err = client.UpdateDatalake("", updateReq)
+	_, err = client.UpdateDatalake(ctx, "", updateReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test UpdateDatalake with invalid UUID
-	_, err = client.UpdateDatalake("invalid-uuid", updateReq)
+	_, err = client.UpdateDatalake(ctx, "invalid-uuid", updateReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test DeleteDatalake with empty ID
-	err = client.DeleteDatalake("")
+	err = client.DeleteDatalake(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test DeleteDatalake with invalid UUID
-	err = client.DeleteDatalake("invalid-uuid")
+	err = client.DeleteDatalake(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+package traceforce
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// debugDumper writes full request/response exchanges to a writer, with the
+// Authorization header and secret fields masked.
+type debugDumper struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (d *debugDumper) dumpRequest(req *http.Request) {
+	clone := req.Clone(req.Context())
+	for k := range clone.Header {
+		if strings.EqualFold(k, "Authorization") || isSecretField(k) {
+			clone.Header.Set(k, redactedSecret)
+		}
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+	// The body is dumped separately so that it can be masked
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+
+	head, err := httputil.DumpRequestOut(clone, false)
+	if err != nil {
+		d.write("request", []byte(fmt.Sprintf("failed to dump request: %v\n", err)), nil)
+		return
+	}
+	d.write("request", head, body)
+}
+
+// dumpResponse dumps resp and replaces its body with an unread copy.
+func (d *debugDumper) dumpResponse(resp *http.Response) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		d.write("response", []byte(fmt.Sprintf("failed to read response body: %v\n", err)), nil)
+		return
+	}
+
+	head, err := httputil.DumpResponse(&http.Response{
+		Status:        resp.Status,
+		StatusCode:    resp.StatusCode,
+		Proto:         resp.Proto,
+		ProtoMajor:    resp.ProtoMajor,
+		ProtoMinor:    resp.ProtoMinor,
+		Header:        resp.Header,
+		ContentLength: resp.ContentLength,
+	}, false)
+	if err != nil {
+		d.write("response", []byte(fmt.Sprintf("failed to dump response: %v\n", err)), nil)
+		return
+	}
+	d.write("response", head, body)
+}
+
+func (d *debugDumper) write(kind string, head, body []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fmt.Fprintf(d.w, "---- traceforce %s ----\n", kind)
+	d.w.Write(head)
+	if len(body) > 0 {
+		d.w.Write(maskBody(body))
+		fmt.Fprintln(d.w)
+	}
+}
+
+// maskBody masks secret fields of a JSON body. Other bodies are returned
+// unchanged.
+func maskBody(data []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	masked, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return data
+	}
+	return masked
+}
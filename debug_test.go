@@ -0,0 +1,65 @@
+package traceforce
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "env"}`))
+	}))
+	defer server.Close()
+
+	var dump bytes.Buffer
+	client, err := NewClient("test-api-key", server.URL, &ClientOptions{DebugWriter: &dump})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	err = client.PostConnection(context.Background(), "550e8400-e29b-41d4-a716-446655440000", &PostConnectionRequest{
+		Infrastructure: &Infrastructure{
+			Salesforce: &SalesforceInfrastructure{
+				ClientID:     "client-id",
+				ClientSecret: NewSecret("super-secret-value"),
+			},
+		},
+		TerraformModuleVersions: "{}",
+	})
+	assert.NoError(t, err)
+
+	out := dump.String()
+	assert.Contains(t, out, "---- traceforce request ----")
+	assert.Contains(t, out, "POST /hosting-environments/550e8400-e29b-41d4-a716-446655440000/post-connection HTTP/1.1")
+	assert.Contains(t, out, "Authorization: "+redactedSecret)
+	assert.Contains(t, out, `"salesforce_client_id":"client-id"`)
+	assert.Contains(t, out, "---- traceforce response ----")
+	assert.Contains(t, out, "HTTP/1.1 200 OK")
+	assert.Contains(t, out, `"name":"env"`)
+
+	assert.NotContains(t, out, "test-api-key")
+	assert.NotContains(t, out, "super-secret-value")
+}
+
+func TestDebugDumpKeepsResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "lake"}`))
+	}))
+	defer server.Close()
+
+	var dump bytes.Buffer
+	client, err := NewClient("test-api-key", server.URL, &ClientOptions{DebugWriter: &dump})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	datalake, err := client.GetDatalake(context.Background(), "550e8400-e29b-41d4-a716-446655440000")
+	assert.NoError(t, err)
+	assert.Equal(t, "lake", datalake.Name)
+}
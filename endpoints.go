@@ -0,0 +1,103 @@
+package traceforce
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultFailbackInterval = 5 * time.Minute
+
+// endpointSet tracks which of the API base URLs requests should go to. The
+// first URL is the primary. After a failover the healthy endpoint stays
+// preferred, and the primary is tried again once per failback interval.
+type endpointSet struct {
+	urls             []string
+	failbackInterval time.Duration
+
+	mu        sync.Mutex
+	preferred int
+	// probedAt is when the primary was last given up on
+	probedAt time.Time
+}
+
+func newEndpointSet(urls []string, failbackInterval time.Duration) *endpointSet {
+	if failbackInterval == 0 {
+		failbackInterval = defaultFailbackInterval
+	}
+	return &endpointSet{urls: urls, failbackInterval: failbackInterval}
+}
+
+// order returns the endpoints in the order they should be tried. With probe
+// set, the primary is put first once per failback interval after a failover
+// so it can be re-probed; only requests that may fail over should probe.
+func (e *endpointSet) order(probe bool) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	probing := probe && e.preferred != 0 && time.Since(e.probedAt) >= e.failbackInterval
+
+	order := make([]string, 0, len(e.urls))
+	if probing {
+		order = append(order, e.urls[0])
+	}
+	order = append(order, e.urls[e.preferred])
+	for i, url := range e.urls {
+		if i == e.preferred || (i == 0 && probing) {
+			continue
+		}
+		order = append(order, url)
+	}
+	return order
+}
+
+// succeeded makes url the preferred endpoint.
+func (e *endpointSet) succeeded(url string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	i := e.index(url)
+	if i < 0 || i == e.preferred {
+		return
+	}
+	if e.preferred == 0 {
+		e.probedAt = time.Now()
+	}
+	e.preferred = i
+}
+
+// failed records that url could not serve a request.
+func (e *endpointSet) failed(url string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.index(url) == 0 {
+		e.probedAt = time.Now()
+	}
+}
+
+func (e *endpointSet) index(url string) int {
+	for i, u := range e.urls {
+		if u == url {
+			return i
+		}
+	}
+	return -1
+}
+
+// isIdempotent reports whether a request may safely be sent more than once.
+func isIdempotent(method string, header http.Header) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return header.Get(idempotencyKeyHeader) != ""
+}
+
+// isFailoverStatus reports whether a response status means the endpoint is
+// unhealthy, as opposed to the request being wrong.
+func isFailoverStatus(status int) bool {
+	return status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
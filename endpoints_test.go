@@ -0,0 +1,194 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEndpoint struct {
+	server   *httptest.Server
+	healthy  atomic.Bool
+	requests atomic.Int32
+}
+
+func newTestEndpoint(t *testing.T, healthy bool) *testEndpoint {
+	e := &testEndpoint{}
+	e.healthy.Store(healthy)
+	e.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.requests.Add(1)
+		if !e.healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("unavailable"))
+			return
+		}
+		if r.Method == "POST" {
+			w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(e.server.Close)
+	return e
+}
+
+func TestEndpointFailover(t *testing.T) {
+	primary := newTestEndpoint(t, false)
+	dr := newTestEndpoint(t, true)
+	ctx := context.Background()
+
+	client, err := NewClient("test-key", primary.server.URL, &ClientOptions{
+		FailoverBaseURLs: []string{dr.server.URL},
+		FailbackInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), primary.requests.Load())
+	assert.Equal(t, int32(1), dr.requests.Load())
+
+	// The healthy endpoint is sticky
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), primary.requests.Load())
+	assert.Equal(t, int32(2), dr.requests.Load())
+
+	// Non-idempotent requests go to the preferred endpoint without failover
+	_, err = client.CreateDatalake(ctx, CreateDatalakeRequest{Name: "lake"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), dr.requests.Load())
+}
+
+func TestEndpointFailback(t *testing.T) {
+	primary := newTestEndpoint(t, false)
+	dr := newTestEndpoint(t, true)
+	ctx := context.Background()
+
+	client, err := NewClient("test-key", primary.server.URL, &ClientOptions{
+		FailoverBaseURLs: []string{dr.server.URL},
+		FailbackInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, dr.server.URL, client.endpoints.order(true)[0])
+
+	primary.healthy.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, primary.server.URL, client.endpoints.order(true)[0])
+
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), primary.requests.Load())
+	assert.Equal(t, primary.server.URL, client.endpoints.order(true)[0])
+}
+
+func TestEndpointFailoverErrors(t *testing.T) {
+	primary := newTestEndpoint(t, false)
+	dr := newTestEndpoint(t, false)
+	ctx := context.Background()
+
+	client, err := NewClient("test-key", primary.server.URL, &ClientOptions{
+		FailoverBaseURLs: []string{dr.server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.GetDatalakes(ctx)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, dr.server.URL, apiErr.Endpoint)
+	assert.Contains(t, err.Error(), "HTTP 503: unavailable (endpoint "+dr.server.URL+")")
+	assert.NotEmpty(t, apiErr.RequestID)
+
+	// A single endpoint keeps the plain error format
+	single, err := NewClient("test-key", primary.server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	_, err = single.GetDatalakes(ctx)
+	assert.Equal(t, "HTTP 503: unavailable", err.Error())
+}
+
+func TestEndpointSetOrder(t *testing.T) {
+	endpoints := newEndpointSet([]string{"a", "b", "c"}, time.Hour)
+	assert.Equal(t, []string{"a", "b", "c"}, endpoints.order(true))
+
+	endpoints.failed("a")
+	endpoints.succeeded("c")
+	assert.Equal(t, []string{"c", "a", "b"}, endpoints.order(true))
+
+	endpoints.probedAt = time.Now().Add(-2 * time.Hour)
+	assert.Equal(t, []string{"a", "c", "b"}, endpoints.order(true))
+	assert.Equal(t, []string{"c", "a", "b"}, endpoints.order(false))
+}
+
+func TestEndpointFailbackNonIdempotent(t *testing.T) {
+	primary := newTestEndpoint(t, false)
+	dr := newTestEndpoint(t, true)
+	ctx := context.Background()
+
+	client, err := NewClient("test-key", primary.server.URL, &ClientOptions{
+		FailoverBaseURLs: []string{dr.server.URL},
+		FailbackInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	// Failback probes are left to idempotent requests
+	_, err = client.CreateDatalake(ctx, CreateDatalakeRequest{Name: "lake"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), primary.requests.Load())
+	assert.Equal(t, int32(2), dr.requests.Load())
+
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), primary.requests.Load())
+	assert.Equal(t, int32(3), dr.requests.Load())
+}
+
+func TestFailoverRegion(t *testing.T) {
+	_, err := NewClient("test-key", "", &ClientOptions{
+		Region:           RegionEU,
+		FailoverBaseURLs: []string{"https://api.traceforce.co/api/v1/"},
+	})
+	assert.EqualError(t, err, "failover URL https://api.traceforce.co/api/v1/ is the endpoint of the us region, not of eu")
+
+	// Other URLs, such as a proxy, are the caller's responsibility
+	client, err := NewClient("test-key", "", &ClientOptions{
+		Region:           RegionEU,
+		FailoverBaseURLs: []string{"https://eu-proxy.example.com", regionBaseURLs[RegionEU]},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, client.endpoints.urls, 3)
+}
+
+func TestIsIdempotent(t *testing.T) {
+	assert.True(t, isIdempotent("GET", http.Header{}))
+	assert.True(t, isIdempotent("DELETE", http.Header{}))
+	assert.False(t, isIdempotent("POST", http.Header{}))
+	assert.False(t, isIdempotent("PATCH", http.Header{}))
+
+	header := http.Header{}
+	header.Set(idempotencyKeyHeader, "key")
+	assert.True(t, isIdempotent("POST", header))
+}
@@ -0,0 +1,249 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// EnsureAction reports what an Ensure helper did.
+type EnsureAction string
+
+const (
+	// EnsureCreated means the resource did not exist and was created.
+	EnsureCreated EnsureAction = "created"
+	// EnsureUpdated means the resource existed and drifted fields were
+	// updated.
+	EnsureUpdated EnsureAction = "updated"
+	// EnsureUnchanged means the resource existed as requested.
+	EnsureUnchanged EnsureAction = "unchanged"
+)
+
+// The Ensure helpers make onboarding idempotent. They look a resource up by
+// its natural identifiers and return it, update its name, labels and
+// annotations (and settings) where they drifted from the request, or create
+// it. Labels, annotations and settings are only compared when the request
+// sets them. When several resources match, the one named like the request is
+// chosen, and an *AmbiguousMatchError is returned if that does not settle it.
+// Concurrent calls for the same resource may both create it.
+
+// EnsureHostingEnvironment returns the hosting environment with the cloud
+// provider and native ID of req, creating or updating it as needed.
+func (c *Client) EnsureHostingEnvironment(ctx context.Context, req CreateHostingEnvironmentRequest, opts ...CallOption) (*HostingEnvironment, EnsureAction, error) {
+	existing, err := c.lookupHostingEnvironment(ctx, req, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if existing == nil {
+		created, err := c.CreateHostingEnvironment(ctx, req, opts...)
+		if err != nil {
+			return nil, "", err
+		}
+		return created, EnsureCreated, nil
+	}
+
+	if hostingEnvironmentInSync(existing, req) {
+		return existing, EnsureUnchanged, nil
+	}
+	updated, err := c.UpdateHostingEnvironmentWithRetry(ctx, existing.ID, func(e *HostingEnvironment) error {
+		e.Name = req.Name
+		e.Labels = syncedLabels(e.Labels, req.Labels)
+		e.Annotations = syncedLabels(e.Annotations, req.Annotations)
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return updated, EnsureUpdated, nil
+}
+
+// EnsureDatalake returns the datalake of type req.Type for
+// req.EnvironmentNativeID in req.HostingEnvironmentID, creating or updating
+// it as needed.
+func (c *Client) EnsureDatalake(ctx context.Context, req CreateDatalakeRequest, opts ...CallOption) (*Datalake, EnsureAction, error) {
+	existing, err := c.lookupDatalake(ctx, req, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if existing == nil {
+		created, err := c.CreateDatalake(ctx, req, opts...)
+		if err != nil {
+			return nil, "", err
+		}
+		return created, EnsureCreated, nil
+	}
+
+	if datalakeInSync(existing, req) {
+		return existing, EnsureUnchanged, nil
+	}
+	updated, err := c.UpdateDatalakeWithRetry(ctx, existing.ID, func(d *Datalake) error {
+		d.Name = req.Name
+		d.Labels = syncedLabels(d.Labels, req.Labels)
+		d.Annotations = syncedLabels(d.Annotations, req.Annotations)
+		if req.Settings != nil {
+			d.Settings = cloneSettings(req.Settings)
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return updated, EnsureUpdated, nil
+}
+
+// EnsureSourceApp returns the source app of type req.Type in
+// req.HostingEnvironmentID, creating or updating it as needed.
+func (c *Client) EnsureSourceApp(ctx context.Context, req CreateSourceAppRequest, opts ...CallOption) (*SourceApp, EnsureAction, error) {
+	existing, err := c.lookupSourceApp(ctx, req, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if existing == nil {
+		created, err := c.CreateSourceApp(ctx, req, opts...)
+		if err != nil {
+			return nil, "", err
+		}
+		return created, EnsureCreated, nil
+	}
+
+	if sourceAppInSync(existing, req) {
+		return existing, EnsureUnchanged, nil
+	}
+	updated, err := c.UpdateSourceAppWithRetry(ctx, existing.ID, func(s *SourceApp) error {
+		s.Name = req.Name
+		s.Labels = syncedLabels(s.Labels, req.Labels)
+		s.Annotations = syncedLabels(s.Annotations, req.Annotations)
+		if req.Settings != nil {
+			s.Settings = cloneSettings(req.Settings)
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return updated, EnsureUpdated, nil
+}
+
+// EnsureSourceAppDatalakeLink returns the link between req.SourceAppID and
+// req.DatalakeID, creating it if needed. Links cannot be updated, so an
+// existing link is always returned unchanged.
+func (c *Client) EnsureSourceAppDatalakeLink(ctx context.Context, req CreateSourceAppDatalakeLinkRequest, opts ...CallOption) (*SourceAppDatalakeLink, EnsureAction, error) {
+	existing, err := c.lookupSourceAppDatalakeLink(ctx, req, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if existing != nil {
+		return existing, EnsureUnchanged, nil
+	}
+	created, err := c.CreateSourceAppDatalakeLink(ctx, req, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, EnsureCreated, nil
+}
+
+// The lookup functions return the resource an Ensure helper would reconcile,
+// or nil if it would create one.
+
+func (c *Client) lookupHostingEnvironment(ctx context.Context, req CreateHostingEnvironmentRequest, opts []CallOption) (*HostingEnvironment, error) {
+	environments, err := c.GetHostingEnvironments(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return notFoundAsNil(single(matching(environments, func(e HostingEnvironment) bool {
+		return e.CloudProvider == req.CloudProvider && e.NativeID == req.NativeID
+	}), "hosting environment", fmt.Sprintf("cloud provider %q and native ID %q", req.CloudProvider, req.NativeID)))
+}
+
+func (c *Client) lookupDatalake(ctx context.Context, req CreateDatalakeRequest, opts []CallOption) (*Datalake, error) {
+	datalakes, err := c.GetDatalakesByHostingEnvironment(ctx, req.HostingEnvironmentID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	found := matching(datalakes, func(d Datalake) bool {
+		return d.Type == req.Type && d.EnvironmentNativeID == req.EnvironmentNativeID
+	})
+	found = preferNamed(found, func(d Datalake) bool { return d.Name == req.Name })
+	return notFoundAsNil(single(found, "datalake", fmt.Sprintf("type %q and environment native ID %q", req.Type, req.EnvironmentNativeID)))
+}
+
+func (c *Client) lookupSourceApp(ctx context.Context, req CreateSourceAppRequest, opts []CallOption) (*SourceApp, error) {
+	sourceApps, err := c.GetSourceAppsByHostingEnvironment(ctx, req.HostingEnvironmentID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	found := matching(sourceApps, func(s SourceApp) bool {
+		return s.Type == req.Type
+	})
+	found = preferNamed(found, func(s SourceApp) bool { return s.Name == req.Name })
+	return notFoundAsNil(single(found, "source app", fmt.Sprintf("type %q", req.Type)))
+}
+
+func (c *Client) lookupSourceAppDatalakeLink(ctx context.Context, req CreateSourceAppDatalakeLinkRequest, opts []CallOption) (*SourceAppDatalakeLink, error) {
+	links, err := c.GetSourceAppDatalakeLinksBySourceApp(ctx, req.SourceAppID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return notFoundAsNil(single(matching(links, func(l SourceAppDatalakeLink) bool {
+		return l.DatalakeID == req.DatalakeID
+	}), "source app datalake link", fmt.Sprintf("datalake ID %q", req.DatalakeID)))
+}
+
+// preferNamed breaks a tie between several resources with the same natural
+// key by keeping those named like the request. found is returned unchanged
+// when none is, so that the caller reports every match as ambiguous.
+func preferNamed[T any](found []T, named func(T) bool) []T {
+	if len(found) < 2 {
+		return found
+	}
+	if tied := matching(found, named); len(tied) > 0 {
+		return tied
+	}
+	return found
+}
+
+func notFoundAsNil[T any](item *T, err error) (*T, error) {
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return item, err
+}
+
+// The InSync functions report whether an existing resource matches the
+// request of an Ensure helper.
+
+func hostingEnvironmentInSync(existing *HostingEnvironment, req CreateHostingEnvironmentRequest) bool {
+	return existing.Name == req.Name && labelsInSync(existing.Labels, req.Labels) && labelsInSync(existing.Annotations, req.Annotations)
+}
+
+func datalakeInSync(existing *Datalake, req CreateDatalakeRequest) bool {
+	return existing.Name == req.Name && labelsInSync(existing.Labels, req.Labels) &&
+		labelsInSync(existing.Annotations, req.Annotations) && settingsInSync(existing.Settings, req.Settings)
+}
+
+func sourceAppInSync(existing *SourceApp, req CreateSourceAppRequest) bool {
+	return existing.Name == req.Name && labelsInSync(existing.Labels, req.Labels) &&
+		labelsInSync(existing.Annotations, req.Annotations) && settingsInSync(existing.Settings, req.Settings)
+}
+
+// labelsInSync reports whether existing labels or annotations match the
+// requested ones. Unset requested labels always match.
+func labelsInSync(existing, requested map[string]string) bool {
+	return requested == nil || mapsEqual(existing, requested)
+}
+
+func settingsInSync(existing, requested map[string]interface{}) bool {
+	return requested == nil || settingsEqual(existing, requested)
+}
+
+// syncedLabels returns the labels or annotations a resource should have.
+func syncedLabels(existing, requested map[string]string) map[string]string {
+	if requested == nil {
+		return existing
+	}
+	return cloneMap(requested)
+}
@@ -0,0 +1,139 @@
+package traceforce
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureHostingEnvironment(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	ctx := context.Background()
+
+	req := CreateHostingEnvironmentRequest{
+		Name:          "prod",
+		Type:          HostingEnvironmentTypeCustomerManaged,
+		CloudProvider: CloudProviderAWS,
+		NativeID:      "123456789012",
+	}
+	created, action, err := client.EnsureHostingEnvironment(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureCreated, action)
+
+	existing, action, err := client.EnsureHostingEnvironment(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUnchanged, action)
+	assert.Equal(t, created.ID, existing.ID)
+
+	req.Name = "production"
+	req.Labels = map[string]string{"team": "platform"}
+	updated, action, err := client.EnsureHostingEnvironment(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUpdated, action)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, "production", updated.Name)
+	assert.Equal(t, "platform", updated.Labels["team"])
+	assert.Equal(t, 1, api.count("POST", "/hosting-environments"))
+
+	// Another native ID is another environment
+	req.NativeID = "210987654321"
+	_, action, err = client.EnsureHostingEnvironment(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureCreated, action)
+}
+
+func TestEnsureDatalakeAndSourceApp(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	ctx := context.Background()
+	env := api.addEnvironment(HostingEnvironment{Name: "prod", CloudProvider: CloudProviderGCP, NativeID: "project"})
+
+	lakeReq := CreateDatalakeRequest{
+		HostingEnvironmentID: env.ID,
+		Type:                 DatalakeTypeBigQuery,
+		Name:                 "lake",
+		EnvironmentNativeID:  "project",
+		Region:               "us-central1",
+	}
+	lake, action, err := client.EnsureDatalake(ctx, lakeReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureCreated, action)
+
+	lakeReq.Region = "us-east1"
+	_, action, err = client.EnsureDatalake(ctx, lakeReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUnchanged, action, "only name, labels, annotations and settings are reconciled")
+
+	lakeReq.Settings = map[string]interface{}{"retention_days": float64(30)}
+	updatedLake, action, err := client.EnsureDatalake(ctx, lakeReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUpdated, action)
+	assert.Equal(t, lake.ID, updatedLake.ID)
+	assert.Equal(t, float64(30), updatedLake.Settings["retention_days"])
+
+	appReq := CreateSourceAppRequest{HostingEnvironmentID: env.ID, Type: SourceAppTypeSalesforce, Name: "sf"}
+	app, action, err := client.EnsureSourceApp(ctx, appReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureCreated, action)
+
+	// Drifted names are updated rather than creating another resource
+	appReq.Name = "salesforce"
+	renamed, action, err := client.EnsureSourceApp(ctx, appReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUpdated, action)
+	assert.Equal(t, app.ID, renamed.ID)
+	assert.Equal(t, "salesforce", renamed.Name)
+
+	lakeReq.Name = "analytics"
+	renamedLake, action, err := client.EnsureDatalake(ctx, lakeReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUpdated, action)
+	assert.Equal(t, lake.ID, renamedLake.ID)
+	assert.Equal(t, "analytics", renamedLake.Name)
+	assert.Equal(t, 1, api.count("POST", "/datalakes"))
+	assert.Equal(t, 1, api.count("POST", "/source-apps"))
+
+	linkReq := CreateSourceAppDatalakeLinkRequest{SourceAppID: app.ID, DatalakeID: lake.ID}
+	link, action, err := client.EnsureSourceAppDatalakeLink(ctx, linkReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureCreated, action)
+
+	again, action, err := client.EnsureSourceAppDatalakeLink(ctx, linkReq)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUnchanged, action)
+	assert.Equal(t, link.ID, again.ID)
+	assert.Equal(t, 1, api.count("POST", "/source-apps-datalakes"))
+}
+
+func TestEnsureAmbiguous(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	env := api.addEnvironment(HostingEnvironment{Name: "prod"})
+	a := api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Type: SourceAppTypeSalesforce, Name: "a"})
+	api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Type: SourceAppTypeSalesforce, Name: "b"})
+	ctx := context.Background()
+
+	// The name breaks the tie
+	req := CreateSourceAppRequest{HostingEnvironmentID: env.ID, Type: SourceAppTypeSalesforce, Name: "a"}
+	app, action, err := client.EnsureSourceApp(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, EnsureUnchanged, action)
+	assert.Equal(t, a.ID, app.ID)
+
+	req.Name = "c"
+	_, _, err = client.EnsureSourceApp(ctx, req)
+	var ambiguous *AmbiguousMatchError
+	if assert.ErrorAs(t, err, &ambiguous) {
+		assert.Len(t, ambiguous.IDs, 2)
+	}
+
+	api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Type: SourceAppTypeSalesforce, Name: "a"})
+	req.Name = "a"
+	_, _, err = client.EnsureSourceApp(ctx, req)
+	if assert.ErrorAs(t, err, &ambiguous) {
+		assert.Len(t, ambiguous.IDs, 2)
+	}
+	assert.Equal(t, 0, api.count("POST", "/source-apps"))
+}
@@ -0,0 +1,91 @@
+package traceforce
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrPreconditionFailed matches API errors for updates rejected with 412
+// Precondition Failed because the resource changed since it was read.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrNotFound matches errors for resources that do not exist, both
+// *NotFoundError from the Find helpers and 404 responses.
+var ErrNotFound = errors.New("not found")
+
+// NotFoundError is returned when no resource matches a lookup.
+type NotFoundError struct {
+	// Kind is the kind of resource, e.g. "datalake".
+	Kind string
+	// Query describes the lookup, e.g. `name "lake"`.
+	Query string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no %s found with %s", e.Kind, e.Query)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// AmbiguousMatchError is returned when a lookup that expects a single
+// resource matches several.
+type AmbiguousMatchError struct {
+	Kind  string
+	Query string
+	// IDs are the IDs of the matching resources.
+	IDs []string
+}
+
+func (e *AmbiguousMatchError) Error() string {
+	return fmt.Sprintf("%d %ss found with %s: %s", len(e.IDs), e.Kind, e.Query, strings.Join(e.IDs, ", "))
+}
+
+// APIError is returned when the API responds with an error status.
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RequestID is the X-Request-ID of the failed request.
+	RequestID string
+	// Endpoint is the base URL that served the request. It is only set when
+	// the client has failover endpoints.
+	Endpoint string
+}
+
+func (e *APIError) Error() string {
+	if e.Endpoint != "" {
+		return fmt.Sprintf("HTTP %d: %s (endpoint %s)", e.StatusCode, e.Body, e.Endpoint)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// Is makes errors.Is report 412 responses as ErrPreconditionFailed and 404
+// responses as ErrNotFound.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+func validateResponse(resp *http.Response) error {
+	if resp.StatusCode >= 400 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read error response body: %v", err)
+		}
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RequestID:  resp.Request.Header.Get(requestIDHeader),
+		}
+	}
+	return nil
+}
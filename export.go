@@ -0,0 +1,601 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentVersion is the version of the export document format.
+const DocumentVersion = 1
+
+// Document is a portable description of the hosting environments, datalakes,
+// source apps and links of a tenant. Resources refer to each other by
+// symbolic references instead of IDs, so a document can be imported into
+// another tenant. It encodes to YAML and JSON:
+//
+//	version: 1
+//	hosting_environments:
+//	  - ref: prod
+//	    name: prod
+//	    type: customer_managed
+//	    cloud_provider: gcp
+//	    native_id: my-project
+//	    datalakes:
+//	      - ref: prod/analytics
+//	        type: bigquery
+//	        name: analytics
+//	        environment_native_id: my-project
+//	        region: us-central1
+//	    source_apps:
+//	      - ref: prod/salesforce
+//	        type: salesforce
+//	        name: salesforce
+//	        links:
+//	          - datalake: prod/analytics
+type Document struct {
+	Version             int                          `json:"version" yaml:"version"`
+	HostingEnvironments []HostingEnvironmentDocument `json:"hosting_environments" yaml:"hosting_environments"`
+}
+
+// HostingEnvironmentDocument is a hosting environment and its children in a
+// Document.
+type HostingEnvironmentDocument struct {
+	Ref           string                 `json:"ref" yaml:"ref"`
+	Name          string                 `json:"name" yaml:"name"`
+	Type          HostingEnvironmentType `json:"type" yaml:"type"`
+	CloudProvider CloudProvider          `json:"cloud_provider" yaml:"cloud_provider"`
+	NativeID      string                 `json:"native_id" yaml:"native_id"`
+	Labels        map[string]string      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations   map[string]string      `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Datalakes     []DatalakeDocument     `json:"datalakes,omitempty" yaml:"datalakes,omitempty"`
+	SourceApps    []SourceAppDocument    `json:"source_apps,omitempty" yaml:"source_apps,omitempty"`
+}
+
+// DatalakeDocument is a datalake in a Document.
+type DatalakeDocument struct {
+	Ref                 string                 `json:"ref" yaml:"ref"`
+	Type                DatalakeType           `json:"type" yaml:"type"`
+	Name                string                 `json:"name" yaml:"name"`
+	EnvironmentNativeID string                 `json:"environment_native_id" yaml:"environment_native_id"`
+	Region              string                 `json:"region" yaml:"region"`
+	Labels              map[string]string      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations         map[string]string      `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Settings            map[string]interface{} `json:"settings,omitempty" yaml:"settings,omitempty"`
+}
+
+// SourceAppDocument is a source app and its links in a Document.
+type SourceAppDocument struct {
+	Ref         string                 `json:"ref" yaml:"ref"`
+	Type        SourceAppType          `json:"type" yaml:"type"`
+	Name        string                 `json:"name" yaml:"name"`
+	Labels      map[string]string      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string      `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Settings    map[string]interface{} `json:"settings,omitempty" yaml:"settings,omitempty"`
+	Links       []LinkDocument         `json:"links,omitempty" yaml:"links,omitempty"`
+}
+
+// LinkDocument is a link from a source app to a datalake in a Document.
+type LinkDocument struct {
+	// Datalake is the reference of the datalake.
+	Datalake    string            `json:"datalake" yaml:"datalake"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// ParseDocument decodes a Document from YAML or JSON.
+func ParseDocument(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %v", err)
+	}
+	if doc.Version != DocumentVersion {
+		return nil, fmt.Errorf("unsupported document version %d", doc.Version)
+	}
+	return &doc, nil
+}
+
+// YAML encodes the document as YAML.
+func (d *Document) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+// JSON encodes the document as indented JSON.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// Export returns every hosting environment, datalake, source app and link as
+// a Document. Datalakes and source apps of missing hosting environments and
+// links to missing resources are left out.
+func (c *Client) Export(ctx context.Context, opts ...CallOption) (*Document, error) {
+	inv, err := c.fetchInventory(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newDocument(inv), nil
+}
+
+func newDocument(inv *inventory) *Document {
+	refs := make(map[string]string)
+	used := make(map[string]bool)
+	assign := func(id, ref string) string {
+		ref = uniqueRef(ref, used)
+		refs[id] = ref
+		return ref
+	}
+
+	environments := append([]HostingEnvironment(nil), inv.environments...)
+	sort.Slice(environments, func(i, j int) bool {
+		return refOrder(environments[i].Name, environments[i].ID, environments[j].Name, environments[j].ID)
+	})
+	datalakes := append([]Datalake(nil), inv.datalakes...)
+	sort.Slice(datalakes, func(i, j int) bool {
+		return refOrder(datalakes[i].Name, datalakes[i].ID, datalakes[j].Name, datalakes[j].ID)
+	})
+	sourceApps := append([]SourceApp(nil), inv.sourceApps...)
+	sort.Slice(sourceApps, func(i, j int) bool {
+		return refOrder(sourceApps[i].Name, sourceApps[i].ID, sourceApps[j].Name, sourceApps[j].ID)
+	})
+
+	doc := &Document{Version: DocumentVersion, HostingEnvironments: []HostingEnvironmentDocument{}}
+	index := make(map[string]int)
+	for _, e := range environments {
+		index[e.ID] = len(doc.HostingEnvironments)
+		doc.HostingEnvironments = append(doc.HostingEnvironments, HostingEnvironmentDocument{
+			Ref:           assign(e.ID, slug(e.Name, "environment")),
+			Name:          e.Name,
+			Type:          e.Type,
+			CloudProvider: e.CloudProvider,
+			NativeID:      e.NativeID,
+			Labels:        cloneMap(e.Labels),
+			Annotations:   cloneMap(e.Annotations),
+		})
+	}
+	for _, d := range datalakes {
+		i, ok := index[d.HostingEnvironmentID]
+		if !ok {
+			continue
+		}
+		env := &doc.HostingEnvironments[i]
+		env.Datalakes = append(env.Datalakes, DatalakeDocument{
+			Ref:                 assign(d.ID, env.Ref+"/"+slug(d.Name, "datalake")),
+			Type:                d.Type,
+			Name:                d.Name,
+			EnvironmentNativeID: d.EnvironmentNativeID,
+			Region:              d.Region,
+			Labels:              cloneMap(d.Labels),
+			Annotations:         cloneMap(d.Annotations),
+			Settings:            cloneSettings(d.Settings),
+		})
+	}
+
+	links := make(map[string][]LinkDocument)
+	for _, l := range inv.links {
+		if ref, ok := refs[l.DatalakeID]; ok {
+			links[l.SourceAppID] = append(links[l.SourceAppID], LinkDocument{
+				Datalake:    ref,
+				Labels:      cloneMap(l.Labels),
+				Annotations: cloneMap(l.Annotations),
+			})
+		}
+	}
+	for _, s := range sourceApps {
+		i, ok := index[s.HostingEnvironmentID]
+		if !ok {
+			continue
+		}
+		env := &doc.HostingEnvironments[i]
+		sourceApp := SourceAppDocument{
+			Ref:         assign(s.ID, env.Ref+"/"+slug(s.Name, "source-app")),
+			Type:        s.Type,
+			Name:        s.Name,
+			Labels:      cloneMap(s.Labels),
+			Annotations: cloneMap(s.Annotations),
+			Settings:    cloneSettings(s.Settings),
+			Links:       links[s.ID],
+		}
+		sort.Slice(sourceApp.Links, func(i, j int) bool {
+			return sourceApp.Links[i].Datalake < sourceApp.Links[j].Datalake
+		})
+		env.SourceApps = append(env.SourceApps, sourceApp)
+	}
+	return doc
+}
+
+func refOrder(nameA, idA, nameB, idB string) bool {
+	if nameA != nameB {
+		return nameA < nameB
+	}
+	return idA < idB
+}
+
+// slug turns a name into a reference, using fallback for names without
+// letters or digits.
+func slug(name, fallback string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return fallback
+	}
+	return b.String()
+}
+
+// uniqueRef numbers ref if it is already used.
+func uniqueRef(ref string, used map[string]bool) string {
+	unique := ref
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", ref, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// DryRun reports what Import would do without changing anything.
+	DryRun bool
+	// NativeIDMap replaces the native IDs of hosting environments and the
+	// environment native IDs of datalakes, e.g. to map a staging project
+	// to the production one.
+	NativeIDMap map[string]string
+	// RegionMap replaces the regions of datalakes.
+	RegionMap map[string]string
+}
+
+// ImportResult is what Import did, or would do, with one resource of the
+// document.
+type ImportResult struct {
+	// Kind is "hosting_environment", "datalake", "source_app" or
+	// "source_app_datalake_link".
+	Kind string `json:"kind"`
+	// Ref is the reference of the resource in the document. Links are
+	// referred to as "<source app>-><datalake>".
+	Ref string `json:"ref"`
+	// ID is the ID of the resource. It is empty for resources a dry run
+	// would create.
+	ID     string       `json:"id,omitempty"`
+	Action EnsureAction `json:"action"`
+}
+
+// ImportReport lists the results of Import in document order.
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Results []ImportResult `json:"results"`
+}
+
+// Count returns the number of resources with the given action.
+func (r *ImportReport) Count(action EnsureAction) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// String returns the report as one line per resource followed by totals.
+func (r *ImportReport) String() string {
+	var b strings.Builder
+	for _, result := range r.Results {
+		fmt.Fprintf(&b, "%-9s %s %s\n", result.Action, strings.ReplaceAll(result.Kind, "_", " "), result.Ref)
+	}
+	verb := ""
+	if r.DryRun {
+		verb = "would be "
+	}
+	fmt.Fprintf(&b, "%d %screated, %d %supdated, %d unchanged\n",
+		r.Count(EnsureCreated), verb, r.Count(EnsureUpdated), verb, r.Count(EnsureUnchanged))
+	return b.String()
+}
+
+// Import creates or updates the resources of a Document with the Ensure
+// helpers, so importing the same document twice changes nothing. Resources
+// are matched by their natural identifiers, not by reference, and documents
+// with two resources of the same natural identifiers are rejected. Import
+// stops at the first error and returns the report of what it did until then.
+//
+// opts apply to every call. An idempotency key set with WithIdempotencyKey
+// is not sent as is but as "<key>-<ref>", one per resource of the document.
+func (c *Client) Import(ctx context.Context, doc *Document, options ImportOptions, opts ...CallOption) (*ImportReport, error) {
+	if doc.Version != DocumentVersion {
+		return nil, fmt.Errorf("unsupported document version %d", doc.Version)
+	}
+	im := &importer{c: c, options: options, opts: opts, ids: make(map[string]string),
+		report: &ImportReport{DryRun: options.DryRun, Results: []ImportResult{}}}
+	if err := doc.validate(im); err != nil {
+		return nil, err
+	}
+	for _, env := range doc.HostingEnvironments {
+		if err := im.hostingEnvironment(ctx, env); err != nil {
+			return im.report, fmt.Errorf("failed to import %s: %w", env.Ref, err)
+		}
+	}
+	// Links go last, since they may refer to datalakes of later environments
+	for _, env := range doc.HostingEnvironments {
+		for _, sourceApp := range env.SourceApps {
+			for _, link := range sourceApp.Links {
+				if err := im.link(ctx, sourceApp.Ref, link); err != nil {
+					return im.report, fmt.Errorf("failed to import %s->%s: %w", sourceApp.Ref, link.Datalake, err)
+				}
+			}
+		}
+	}
+	return im.report, nil
+}
+
+// validate checks that references are unique, links refer to datalakes of
+// the document, and no two resources share the natural identifiers the
+// Ensure helpers match on once remapped, since they would be imported as one.
+func (d *Document) validate(im *importer) error {
+	refs := make(map[string]bool)
+	datalakes := make(map[string]bool)
+	add := func(ref string) error {
+		if ref == "" {
+			return fmt.Errorf("document contains a resource without a ref")
+		}
+		if refs[ref] {
+			return fmt.Errorf("document contains duplicate ref %q", ref)
+		}
+		refs[ref] = true
+		return nil
+	}
+	keys := make(map[string]string)
+	unique := func(ref, kind string, key ...interface{}) error {
+		k := fmt.Sprintf("%s %q", kind, key)
+		if other, ok := keys[k]; ok {
+			return fmt.Errorf("%s and %s are the same %s", other, ref, strings.ReplaceAll(kind, "_", " "))
+		}
+		keys[k] = ref
+		return nil
+	}
+
+	for _, env := range d.HostingEnvironments {
+		if err := add(env.Ref); err != nil {
+			return err
+		}
+		if err := unique(env.Ref, "hosting_environment", env.CloudProvider, im.nativeID(env.NativeID)); err != nil {
+			return err
+		}
+		for _, datalake := range env.Datalakes {
+			if err := add(datalake.Ref); err != nil {
+				return err
+			}
+			if err := unique(datalake.Ref, "datalake", env.Ref, datalake.Type, im.nativeID(datalake.EnvironmentNativeID)); err != nil {
+				return err
+			}
+			datalakes[datalake.Ref] = true
+		}
+		for _, sourceApp := range env.SourceApps {
+			if err := add(sourceApp.Ref); err != nil {
+				return err
+			}
+			if err := unique(sourceApp.Ref, "source_app", env.Ref, sourceApp.Type); err != nil {
+				return err
+			}
+		}
+	}
+	for _, env := range d.HostingEnvironments {
+		for _, sourceApp := range env.SourceApps {
+			for _, link := range sourceApp.Links {
+				if !datalakes[link.Datalake] {
+					return fmt.Errorf("source app %q links to unknown datalake %q", sourceApp.Ref, link.Datalake)
+				}
+				ref := sourceApp.Ref + "->" + link.Datalake
+				if err := unique(ref, "source_app_datalake_link", sourceApp.Ref, link.Datalake); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// importer imports a document. ids maps references to the IDs of existing
+// resources; resources a dry run would create have none.
+type importer struct {
+	c       *Client
+	options ImportOptions
+	opts    []CallOption
+	ids     map[string]string
+	report  *ImportReport
+}
+
+func (im *importer) record(kind, ref, id string, action EnsureAction) {
+	if id != "" {
+		im.ids[ref] = id
+	}
+	im.report.Results = append(im.report.Results, ImportResult{Kind: kind, Ref: ref, ID: id, Action: action})
+}
+
+// optsFor returns the options of the calls that import the resource ref.
+// An idempotency key passed to Import is suffixed with ref, so that each
+// resource is created under its own key and importing again with the same
+// key is still deduplicated per resource.
+func (im *importer) optsFor(ref string) []CallOption {
+	key := newCallOptions(im.opts).headers[idempotencyKeyHeader]
+	if key == "" {
+		return im.opts
+	}
+	return appendOptions(im.opts, WithIdempotencyKey(key+"-"+ref))
+}
+
+func (im *importer) nativeID(id string) string {
+	if mapped, ok := im.options.NativeIDMap[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+func (im *importer) region(region string) string {
+	if mapped, ok := im.options.RegionMap[region]; ok {
+		return mapped
+	}
+	return region
+}
+
+func (im *importer) hostingEnvironment(ctx context.Context, env HostingEnvironmentDocument) error {
+	req := CreateHostingEnvironmentRequest{
+		Name:          env.Name,
+		Type:          env.Type,
+		CloudProvider: env.CloudProvider,
+		NativeID:      im.nativeID(env.NativeID),
+		Labels:        env.Labels,
+		Annotations:   env.Annotations,
+	}
+	if im.options.DryRun {
+		existing, err := im.c.lookupHostingEnvironment(ctx, req, im.opts)
+		if err != nil {
+			return err
+		}
+		switch {
+		case existing == nil:
+			im.record("hosting_environment", env.Ref, "", EnsureCreated)
+		case hostingEnvironmentInSync(existing, req):
+			im.record("hosting_environment", env.Ref, existing.ID, EnsureUnchanged)
+		default:
+			im.record("hosting_environment", env.Ref, existing.ID, EnsureUpdated)
+		}
+	} else {
+		environment, action, err := im.c.EnsureHostingEnvironment(ctx, req, im.optsFor(env.Ref)...)
+		if err != nil {
+			return err
+		}
+		im.record("hosting_environment", env.Ref, environment.ID, action)
+	}
+
+	envID := im.ids[env.Ref]
+	for _, datalake := range env.Datalakes {
+		if err := im.datalake(ctx, envID, datalake); err != nil {
+			return fmt.Errorf("failed to import %s: %w", datalake.Ref, err)
+		}
+	}
+	for _, sourceApp := range env.SourceApps {
+		if err := im.sourceApp(ctx, envID, sourceApp); err != nil {
+			return fmt.Errorf("failed to import %s: %w", sourceApp.Ref, err)
+		}
+	}
+	return nil
+}
+
+func (im *importer) datalake(ctx context.Context, envID string, datalake DatalakeDocument) error {
+	req := CreateDatalakeRequest{
+		HostingEnvironmentID: envID,
+		Type:                 datalake.Type,
+		Name:                 datalake.Name,
+		EnvironmentNativeID:  im.nativeID(datalake.EnvironmentNativeID),
+		Region:               im.region(datalake.Region),
+		Labels:               datalake.Labels,
+		Annotations:          datalake.Annotations,
+		Settings:             datalake.Settings,
+	}
+	if im.options.DryRun {
+		var existing *Datalake
+		if envID != "" {
+			var err error
+			if existing, err = im.c.lookupDatalake(ctx, req, im.opts); err != nil {
+				return err
+			}
+		}
+		switch {
+		case existing == nil:
+			im.record("datalake", datalake.Ref, "", EnsureCreated)
+		case datalakeInSync(existing, req):
+			im.record("datalake", datalake.Ref, existing.ID, EnsureUnchanged)
+		default:
+			im.record("datalake", datalake.Ref, existing.ID, EnsureUpdated)
+		}
+		return nil
+	}
+
+	created, action, err := im.c.EnsureDatalake(ctx, req, im.optsFor(datalake.Ref)...)
+	if err != nil {
+		return err
+	}
+	im.record("datalake", datalake.Ref, created.ID, action)
+	return nil
+}
+
+func (im *importer) sourceApp(ctx context.Context, envID string, sourceApp SourceAppDocument) error {
+	req := CreateSourceAppRequest{
+		HostingEnvironmentID: envID,
+		Type:                 sourceApp.Type,
+		Name:                 sourceApp.Name,
+		Labels:               sourceApp.Labels,
+		Annotations:          sourceApp.Annotations,
+		Settings:             sourceApp.Settings,
+	}
+	if im.options.DryRun {
+		var existing *SourceApp
+		if envID != "" {
+			var err error
+			if existing, err = im.c.lookupSourceApp(ctx, req, im.opts); err != nil {
+				return err
+			}
+		}
+		switch {
+		case existing == nil:
+			im.record("source_app", sourceApp.Ref, "", EnsureCreated)
+		case sourceAppInSync(existing, req):
+			im.record("source_app", sourceApp.Ref, existing.ID, EnsureUnchanged)
+		default:
+			im.record("source_app", sourceApp.Ref, existing.ID, EnsureUpdated)
+		}
+		return nil
+	}
+
+	created, action, err := im.c.EnsureSourceApp(ctx, req, im.optsFor(sourceApp.Ref)...)
+	if err != nil {
+		return err
+	}
+	im.record("source_app", sourceApp.Ref, created.ID, action)
+	return nil
+}
+
+func (im *importer) link(ctx context.Context, sourceAppRef string, link LinkDocument) error {
+	ref := sourceAppRef + "->" + link.Datalake
+	req := CreateSourceAppDatalakeLinkRequest{
+		SourceAppID: im.ids[sourceAppRef],
+		DatalakeID:  im.ids[link.Datalake],
+		Labels:      link.Labels,
+		Annotations: link.Annotations,
+	}
+	if im.options.DryRun {
+		var existing *SourceAppDatalakeLink
+		if req.SourceAppID != "" && req.DatalakeID != "" {
+			var err error
+			if existing, err = im.c.lookupSourceAppDatalakeLink(ctx, req, im.opts); err != nil {
+				return err
+			}
+		}
+		if existing == nil {
+			im.record("source_app_datalake_link", ref, "", EnsureCreated)
+		} else {
+			im.record("source_app_datalake_link", ref, existing.ID, EnsureUnchanged)
+		}
+		return nil
+	}
+
+	created, action, err := im.c.EnsureSourceAppDatalakeLink(ctx, req, im.optsFor(ref)...)
+	if err != nil {
+		return err
+	}
+	im.record("source_app_datalake_link", ref, created.ID, action)
+	return nil
+}
@@ -0,0 +1,178 @@
+package traceforce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	staging := newFakeAPI(t)
+	env := staging.addEnvironment(HostingEnvironment{Name: "GCP Staging", Type: HostingEnvironmentTypeCustomerManaged,
+		CloudProvider: CloudProviderGCP, NativeID: "acme-staging", Labels: map[string]string{"team": "data"}})
+	lake := staging.addDatalake(Datalake{HostingEnvironmentID: env.ID, Type: DatalakeTypeBigQuery, Name: "Analytics",
+		EnvironmentNativeID: "acme-staging", Region: "us-east1", Settings: map[string]interface{}{"retention_days": float64(30)}})
+	app := staging.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Type: SourceAppTypeSalesforce, Name: "Salesforce"})
+	staging.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: lake.ID, Labels: map[string]string{"tier": "gold"}})
+	// Dangling links are left out
+	staging.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: "deleted"})
+	ctx := context.Background()
+
+	doc, err := staging.client(t).Export(ctx)
+	assert.NoError(t, err)
+	data, err := doc.YAML()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), env.ID)
+	assert.NotContains(t, string(data), lake.ID)
+
+	doc, err = ParseDocument(data)
+	assert.NoError(t, err)
+	if assert.Len(t, doc.HostingEnvironments, 1) {
+		environment := doc.HostingEnvironments[0]
+		assert.Equal(t, "gcp-staging", environment.Ref)
+		assert.Equal(t, "gcp-staging/analytics", environment.Datalakes[0].Ref)
+		assert.Equal(t, []LinkDocument{{Datalake: "gcp-staging/analytics", Labels: map[string]string{"tier": "gold"}}},
+			environment.SourceApps[0].Links)
+	}
+
+	prod := newFakeAPI(t)
+	client := prod.client(t)
+	options := ImportOptions{
+		DryRun:      true,
+		NativeIDMap: map[string]string{"acme-staging": "acme-prod"},
+		RegionMap:   map[string]string{"us-east1": "us-central1"},
+	}
+	report, err := client.Import(ctx, doc, options)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Count(EnsureCreated))
+	assert.Contains(t, report.String(), "4 would be created, 0 would be updated, 0 unchanged")
+	assert.Equal(t, 0, prod.count("POST", "/hosting-environments"))
+
+	options.DryRun = false
+	report, err = client.Import(ctx, doc, options)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Count(EnsureCreated))
+	environments, _ := client.GetHostingEnvironments(ctx)
+	if assert.Len(t, environments, 1) {
+		assert.Equal(t, "acme-prod", environments[0].NativeID)
+		assert.Equal(t, "data", environments[0].Labels["team"])
+	}
+	datalakes, _ := client.GetDatalakes(ctx)
+	if assert.Len(t, datalakes, 1) {
+		assert.Equal(t, "acme-prod", datalakes[0].EnvironmentNativeID)
+		assert.Equal(t, "us-central1", datalakes[0].Region)
+	}
+	links, _ := client.GetSourceAppDatalakeLinks(ctx)
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, datalakes[0].ID, links[0].DatalakeID)
+	}
+
+	// Importing again changes nothing
+	options.DryRun = true
+	report, err = client.Import(ctx, doc, options)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Count(EnsureUnchanged))
+	options.DryRun = false
+	report, err = client.Import(ctx, doc, options)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Count(EnsureUnchanged))
+	assert.Equal(t, 1, prod.count("POST", "/source-apps-datalakes"))
+}
+
+func TestImportValidation(t *testing.T) {
+	client := newFakeAPI(t).client(t)
+	doc := &Document{Version: DocumentVersion, HostingEnvironments: []HostingEnvironmentDocument{{
+		Ref:        "prod",
+		SourceApps: []SourceAppDocument{{Ref: "prod/app", Links: []LinkDocument{{Datalake: "prod/missing"}}}},
+	}}}
+	_, err := client.Import(context.Background(), doc, ImportOptions{})
+	assert.EqualError(t, err, `source app "prod/app" links to unknown datalake "prod/missing"`)
+
+	doc.Version = 2
+	_, err = client.Import(context.Background(), doc, ImportOptions{})
+	assert.EqualError(t, err, "unsupported document version 2")
+
+	_, err = ParseDocument([]byte(`{"version": 1, "hosting_environments": []}`))
+	assert.NoError(t, err)
+}
+
+func TestSlug(t *testing.T) {
+	assert.Equal(t, "gcp-prod-eu", slug("  GCP Prod (EU) ", "x"))
+	assert.Equal(t, "x", slug("--", "x"))
+	used := map[string]bool{}
+	assert.Equal(t, "a", uniqueRef("a", used))
+	assert.Equal(t, "a-2", uniqueRef("a", used))
+}
+
+func TestExportImportSiblings(t *testing.T) {
+	source := newFakeAPI(t)
+	env := source.addEnvironment(HostingEnvironment{Name: "e", CloudProvider: CloudProviderGCP, NativeID: "project"})
+	lake := source.addDatalake(Datalake{HostingEnvironmentID: env.ID, Type: DatalakeTypeBigQuery, Name: "lake", EnvironmentNativeID: "project"})
+	other := source.addDatalake(Datalake{HostingEnvironmentID: env.ID, Type: DatalakeTypeBigQuery, Name: "other", EnvironmentNativeID: "other-project"})
+	a := source.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Type: SourceAppTypeSalesforce, Name: "sf-a"})
+	source.addLink(SourceAppDatalakeLink{SourceAppID: a.ID, DatalakeID: lake.ID})
+	source.addLink(SourceAppDatalakeLink{SourceAppID: a.ID, DatalakeID: other.ID})
+	ctx := context.Background()
+
+	doc, err := source.client(t).Export(ctx)
+	assert.NoError(t, err)
+
+	// Datalakes of one type in different projects are different datalakes
+	target := newFakeAPI(t)
+	client := target.client(t)
+	report, err := client.Import(ctx, doc, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, report.Count(EnsureCreated))
+	datalakes, _ := client.GetDatalakes(ctx)
+	assert.Len(t, datalakes, 2)
+	links, _ := client.GetSourceAppDatalakeLinks(ctx)
+	assert.Len(t, links, 2)
+
+	// Same-type siblings in one project would be imported as one
+	_, err = client.Import(ctx, doc, ImportOptions{NativeIDMap: map[string]string{"other-project": "project"}})
+	assert.EqualError(t, err, "e/lake and e/other are the same datalake")
+
+	environment := &doc.HostingEnvironments[0]
+	environment.SourceApps = append(environment.SourceApps, SourceAppDocument{Ref: "e/sf-b", Type: SourceAppTypeSalesforce, Name: "sf-b"})
+	_, err = client.Import(ctx, doc, ImportOptions{})
+	assert.EqualError(t, err, "e/sf-a and e/sf-b are the same source app")
+
+	// So would environments remapped to the same native ID
+	doc = &Document{Version: DocumentVersion, HostingEnvironments: []HostingEnvironmentDocument{
+		{Ref: "staging", CloudProvider: CloudProviderAWS, NativeID: "1"},
+		{Ref: "prod", CloudProvider: CloudProviderAWS, NativeID: "2"},
+	}}
+	_, err = client.Import(ctx, doc, ImportOptions{NativeIDMap: map[string]string{"1": "2"}})
+	assert.EqualError(t, err, "staging and prod are the same hosting environment")
+}
+
+func TestImportIdempotencyKey(t *testing.T) {
+	api := newFakeAPI(t)
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			mu.Lock()
+			keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+			mu.Unlock()
+		}
+		api.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	doc := &Document{Version: DocumentVersion, HostingEnvironments: []HostingEnvironmentDocument{
+		{Ref: "staging", CloudProvider: CloudProviderAWS, NativeID: "1"},
+		{Ref: "prod", CloudProvider: CloudProviderAWS, NativeID: "2"},
+	}}
+	_, err = client.Import(context.Background(), doc, ImportOptions{}, WithIdempotencyKey("onboard"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"onboard-staging", "onboard-prod"}, keys)
+}
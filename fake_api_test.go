@@ -0,0 +1,256 @@
+package traceforce
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeAPI is an in-memory implementation of the Traceforce API for tests.
+type fakeAPI struct {
+	mu           sync.Mutex
+	environments map[string]*HostingEnvironment
+	datalakes    map[string]*Datalake
+	sourceApps   map[string]*SourceApp
+	links        map[string]*SourceAppDatalakeLink
+	// requests counts requests by method and path without query
+	requests map[string]int
+
+	server *httptest.Server
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	f := &fakeAPI{
+		environments: make(map[string]*HostingEnvironment),
+		datalakes:    make(map[string]*Datalake),
+		sourceApps:   make(map[string]*SourceApp),
+		links:        make(map[string]*SourceAppDatalakeLink),
+		requests:     make(map[string]int),
+	}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeAPI) client(t *testing.T) *Client {
+	client, err := NewClient("test-key", f.server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func (f *fakeAPI) count(method, path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[method+" "+path]
+}
+
+func (f *fakeAPI) addEnvironment(e HostingEnvironment) *HostingEnvironment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e.ID == "" {
+		e.ID = uuid.NewString()
+	}
+	f.environments[e.ID] = &e
+	return &e
+}
+
+func (f *fakeAPI) addDatalake(d Datalake) *Datalake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d.ID == "" {
+		d.ID = uuid.NewString()
+	}
+	f.datalakes[d.ID] = &d
+	return &d
+}
+
+func (f *fakeAPI) addSourceApp(s SourceApp) *SourceApp {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s.ID == "" {
+		s.ID = uuid.NewString()
+	}
+	f.sourceApps[s.ID] = &s
+	return &s
+}
+
+func (f *fakeAPI) addLink(l SourceAppDatalakeLink) *SourceAppDatalakeLink {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if l.ID == "" {
+		l.ID = uuid.NewString()
+	}
+	f.links[l.ID] = &l
+	return &l
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[r.Method+" "+r.URL.Path]++
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	collection := parts[0]
+	id := ""
+	if len(parts) > 1 {
+		id = parts[1]
+	}
+	query := r.URL.Query()
+	now := time.Now().UTC()
+
+	switch {
+	case r.Method == "GET" && id == "":
+		var items []interface{}
+		switch collection {
+		case "hosting-environments":
+			for _, e := range f.environments {
+				items = append(items, e)
+			}
+		case "datalakes":
+			for _, d := range f.datalakes {
+				if env := query.Get("hosting_environment_id"); env == "" || env == d.HostingEnvironmentID {
+					items = append(items, d)
+				}
+			}
+		case "source-apps":
+			for _, s := range f.sourceApps {
+				if env := query.Get("hosting_environment_id"); env == "" || env == s.HostingEnvironmentID {
+					items = append(items, s)
+				}
+			}
+		case "source-apps-datalakes":
+			for _, l := range f.links {
+				if app := query.Get("source_app_id"); app != "" && app != l.SourceAppID {
+					continue
+				}
+				if lake := query.Get("datalake_id"); lake != "" && lake != l.DatalakeID {
+					continue
+				}
+				items = append(items, l)
+			}
+		}
+		if items == nil {
+			items = []interface{}{}
+		}
+		json.NewEncoder(w).Encode(items)
+
+	case r.Method == "GET":
+		if item := f.lookup(collection, id); item != nil {
+			json.NewEncoder(w).Encode(item)
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+
+	case r.Method == "POST":
+		var item interface{}
+		switch collection {
+		case "hosting-environments":
+			var req CreateHostingEnvironmentRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			e := &HostingEnvironment{ID: uuid.NewString(), Name: req.Name, Type: req.Type, CloudProvider: req.CloudProvider,
+				NativeID: req.NativeID, Status: "pending", Labels: req.Labels, Annotations: req.Annotations, CreatedAt: now, UpdatedAt: now}
+			f.environments[e.ID], item = e, e
+		case "datalakes":
+			var req CreateDatalakeRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			d := &Datalake{ID: uuid.NewString(), HostingEnvironmentID: req.HostingEnvironmentID, Type: req.Type, Name: req.Name,
+				Status: DatalakeStatusPending, EnvironmentNativeID: req.EnvironmentNativeID, Region: req.Region,
+				Labels: req.Labels, Annotations: req.Annotations, Settings: req.Settings, CreatedAt: now, UpdatedAt: now}
+			f.datalakes[d.ID], item = d, d
+		case "source-apps":
+			var req CreateSourceAppRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			s := &SourceApp{ID: uuid.NewString(), HostingEnvironmentID: req.HostingEnvironmentID, Type: req.Type, Name: req.Name,
+				Status: "pending", Labels: req.Labels, Annotations: req.Annotations, Settings: req.Settings, CreatedAt: now, UpdatedAt: now}
+			f.sourceApps[s.ID], item = s, s
+		case "source-apps-datalakes":
+			var req CreateSourceAppDatalakeLinkRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			l := &SourceAppDatalakeLink{ID: uuid.NewString(), SourceAppID: req.SourceAppID, DatalakeID: req.DatalakeID,
+				Labels: req.Labels, Annotations: req.Annotations, CreatedAt: now, UpdatedAt: now}
+			if app, ok := f.sourceApps[req.SourceAppID]; ok {
+				l.HostingEnvironmentID = app.HostingEnvironmentID
+			}
+			f.links[l.ID], item = l, l
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(item)
+
+	case r.Method == "PATCH":
+		item := f.lookup(collection, id)
+		if item == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		// A merge patch of the JSON representation is close enough
+		var current, patch map[string]interface{}
+		data, _ := json.Marshal(item)
+		json.Unmarshal(data, &current)
+		json.NewDecoder(r.Body).Decode(&patch)
+		for k, v := range patch {
+			if v == nil {
+				delete(current, k)
+			} else {
+				current[k] = v
+			}
+		}
+		current["updated_at"] = now
+		data, _ = json.Marshal(current)
+		switch collection {
+		case "hosting-environments":
+			var e HostingEnvironment
+			json.Unmarshal(data, &e)
+			f.environments[id], item = &e, &e
+		case "datalakes":
+			var d Datalake
+			json.Unmarshal(data, &d)
+			f.datalakes[id], item = &d, &d
+		case "source-apps":
+			var s SourceApp
+			json.Unmarshal(data, &s)
+			f.sourceApps[id], item = &s, &s
+		}
+		json.NewEncoder(w).Encode(item)
+
+	case r.Method == "DELETE":
+		if f.lookup(collection, id) == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		delete(f.environments, id)
+		delete(f.datalakes, id)
+		delete(f.sourceApps, id)
+		delete(f.links, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeAPI) lookup(collection, id string) interface{} {
+	switch collection {
+	case "hosting-environments":
+		if e, ok := f.environments[id]; ok {
+			return e
+		}
+	case "datalakes":
+		if d, ok := f.datalakes[id]; ok {
+			return d
+		}
+	case "source-apps":
+		if s, ok := f.sourceApps[id]; ok {
+			return s
+		}
+	case "source-apps-datalakes":
+		if l, ok := f.links[id]; ok {
+			return l
+		}
+	}
+	return nil
+}
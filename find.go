@@ -0,0 +1,95 @@
+package traceforce
+
+import (
+	"context"
+	"fmt"
+)
+
+// FindHostingEnvironmentByNativeID returns the hosting environment with the
+// given native ID, e.g. an AWS account ID or GCP project ID. It returns a
+// *NotFoundError if there is none and an *AmbiguousMatchError if several
+// cloud providers have an environment with that ID.
+func (c *Client) FindHostingEnvironmentByNativeID(ctx context.Context, nativeID string, opts ...CallOption) (*HostingEnvironment, error) {
+	environments, err := c.GetHostingEnvironments(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return single(matching(environments, func(e HostingEnvironment) bool {
+		return e.NativeID == nativeID
+	}), "hosting environment", fmt.Sprintf("native ID %q", nativeID))
+}
+
+// FindHostingEnvironmentsByName returns the hosting environments named name.
+// Names are not unique, so there may be several. It returns a
+// *NotFoundError if there are none.
+func (c *Client) FindHostingEnvironmentsByName(ctx context.Context, name string, opts ...CallOption) ([]HostingEnvironment, error) {
+	environments, err := c.GetHostingEnvironments(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	found := matching(environments, func(e HostingEnvironment) bool {
+		return e.Name == name
+	})
+	if len(found) == 0 {
+		return nil, &NotFoundError{Kind: "hosting environment", Query: fmt.Sprintf("name %q", name)}
+	}
+	return found, nil
+}
+
+// FindDatalakeByName returns the datalake named name in the given hosting
+// environment. It returns a *NotFoundError if there is none and an
+// *AmbiguousMatchError if there are several.
+func (c *Client) FindDatalakeByName(ctx context.Context, hostingEnvironmentID, name string, opts ...CallOption) (*Datalake, error) {
+	datalakes, err := c.GetDatalakesByHostingEnvironment(ctx, hostingEnvironmentID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return single(matching(datalakes, func(d Datalake) bool {
+		return d.Name == name
+	}), "datalake", fmt.Sprintf("name %q", name))
+}
+
+// FindSourceAppByName returns the source app named name in the given
+// hosting environment. It returns a *NotFoundError if there is none and an
+// *AmbiguousMatchError if there are several.
+func (c *Client) FindSourceAppByName(ctx context.Context, hostingEnvironmentID, name string, opts ...CallOption) (*SourceApp, error) {
+	sourceApps, err := c.GetSourceAppsByHostingEnvironment(ctx, hostingEnvironmentID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return single(matching(sourceApps, func(s SourceApp) bool {
+		return s.Name == name
+	}), "source app", fmt.Sprintf("name %q", name))
+}
+
+// identified is implemented by resources with an ID.
+type identified interface {
+	resourceID() string
+}
+
+// matching returns the items for which match is true.
+func matching[T any](items []T, match func(T) bool) []T {
+	var found []T
+	for _, item := range items {
+		if match(item) {
+			found = append(found, item)
+		}
+	}
+	return found
+}
+
+// single returns the only item of found, a *NotFoundError if there is none
+// and an *AmbiguousMatchError if there are several.
+func single[T identified](found []T, kind, query string) (*T, error) {
+	switch len(found) {
+	case 0:
+		return nil, &NotFoundError{Kind: kind, Query: query}
+	case 1:
+		return &found[0], nil
+	}
+	ids := make([]string, 0, len(found))
+	for _, item := range found {
+		ids = append(ids, item.resourceID())
+	}
+	return nil, &AmbiguousMatchError{Kind: kind, Query: query, IDs: ids}
+}
@@ -0,0 +1,76 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindHostingEnvironments(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	ctx := context.Background()
+
+	aws := api.addEnvironment(HostingEnvironment{Name: "prod", CloudProvider: CloudProviderAWS, NativeID: "123456789012"})
+	api.addEnvironment(HostingEnvironment{Name: "prod", CloudProvider: CloudProviderGCP, NativeID: "shared-id"})
+	api.addEnvironment(HostingEnvironment{Name: "staging", CloudProvider: CloudProviderAzure, NativeID: "shared-id"})
+
+	found, err := client.FindHostingEnvironmentByNativeID(ctx, "123456789012")
+	assert.NoError(t, err)
+	assert.Equal(t, aws.ID, found.ID)
+
+	_, err = client.FindHostingEnvironmentByNativeID(ctx, "shared-id")
+	var ambiguous *AmbiguousMatchError
+	if assert.ErrorAs(t, err, &ambiguous) {
+		assert.Equal(t, "hosting environment", ambiguous.Kind)
+		assert.Len(t, ambiguous.IDs, 2)
+	}
+
+	_, err = client.FindHostingEnvironmentByNativeID(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, `no hosting environment found with native ID "missing"`)
+
+	environments, err := client.FindHostingEnvironmentsByName(ctx, "prod")
+	assert.NoError(t, err)
+	assert.Len(t, environments, 2)
+
+	_, err = client.FindHostingEnvironmentsByName(ctx, "dev")
+	var notFound *NotFoundError
+	if assert.ErrorAs(t, err, &notFound) {
+		assert.Equal(t, `name "dev"`, notFound.Query)
+	}
+}
+
+func TestFindByName(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	ctx := context.Background()
+
+	env := api.addEnvironment(HostingEnvironment{Name: "prod"})
+	other := api.addEnvironment(HostingEnvironment{Name: "staging"})
+	lake := api.addDatalake(Datalake{HostingEnvironmentID: env.ID, Name: "lake"})
+	api.addDatalake(Datalake{HostingEnvironmentID: other.ID, Name: "lake"})
+	api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Name: "sf"})
+	api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Name: "sf"})
+
+	found, err := client.FindDatalakeByName(ctx, env.ID, "lake")
+	assert.NoError(t, err)
+	assert.Equal(t, lake.ID, found.ID)
+
+	_, err = client.FindDatalakeByName(ctx, env.ID, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = client.FindSourceAppByName(ctx, env.ID, "sf")
+	var ambiguous *AmbiguousMatchError
+	assert.ErrorAs(t, err, &ambiguous)
+	assert.False(t, errors.Is(err, ErrNotFound))
+
+	_, err = client.FindSourceAppByName(ctx, other.ID, "sf")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// 404 responses match ErrNotFound too
+	_, err = client.GetDatalake(ctx, "550e8400-e29b-41d4-a716-446655440000")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
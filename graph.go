@@ -0,0 +1,406 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NodeKind is the kind of resource a graph node represents.
+type NodeKind string
+
+const (
+	NodeHostingEnvironment NodeKind = "hosting_environment"
+	NodeDatalake           NodeKind = "datalake"
+	NodeSourceApp          NodeKind = "source_app"
+)
+
+// EdgeKind is the relationship a graph edge represents.
+type EdgeKind string
+
+const (
+	// EdgeContains points from a hosting environment to a datalake or source
+	// app in it.
+	EdgeContains EdgeKind = "contains"
+	// EdgeFeeds points from a source app to a datalake it is linked to.
+	EdgeFeeds EdgeKind = "feeds"
+)
+
+// GraphNode is a hosting environment, datalake or source app in a Graph.
+type GraphNode struct {
+	ID     string   `json:"id"`
+	Kind   NodeKind `json:"kind"`
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Status string   `json:"status"`
+	// HostingEnvironmentID is empty for hosting environments.
+	HostingEnvironmentID string `json:"hosting_environment_id,omitempty"`
+}
+
+// Color returns the colour the status of the node is drawn in.
+func (n GraphNode) Color() string {
+	return statusColor(n.Status)
+}
+
+// GraphEdge is a relationship between two nodes of a Graph.
+type GraphEdge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+	// LinkID is the ID of the SourceAppDatalakeLink of a feeds edge.
+	LinkID string `json:"link_id,omitempty"`
+}
+
+// Graph is the topology of hosting environments, datalakes, source apps and
+// the links between them. Links to resources that are not in the graph are
+// left out.
+type Graph struct {
+	nodes map[string]GraphNode
+	edges []GraphEdge
+	out   map[string][]GraphEdge
+	in    map[string][]GraphEdge
+}
+
+// NewGraph builds the graph of the given resources.
+func NewGraph(environments []HostingEnvironment, datalakes []Datalake, sourceApps []SourceApp, links []SourceAppDatalakeLink) *Graph {
+	g := &Graph{
+		nodes: make(map[string]GraphNode),
+		out:   make(map[string][]GraphEdge),
+		in:    make(map[string][]GraphEdge),
+	}
+	for _, e := range environments {
+		g.nodes[e.ID] = GraphNode{ID: e.ID, Kind: NodeHostingEnvironment, Name: e.Name, Type: string(e.Type), Status: string(e.Status)}
+	}
+	for _, d := range datalakes {
+		g.nodes[d.ID] = GraphNode{ID: d.ID, Kind: NodeDatalake, Name: d.Name, Type: string(d.Type), Status: string(d.Status),
+			HostingEnvironmentID: d.HostingEnvironmentID}
+		g.addEdge(GraphEdge{From: d.HostingEnvironmentID, To: d.ID, Kind: EdgeContains})
+	}
+	for _, s := range sourceApps {
+		g.nodes[s.ID] = GraphNode{ID: s.ID, Kind: NodeSourceApp, Name: s.Name, Type: string(s.Type), Status: string(s.Status),
+			HostingEnvironmentID: s.HostingEnvironmentID}
+		g.addEdge(GraphEdge{From: s.HostingEnvironmentID, To: s.ID, Kind: EdgeContains})
+	}
+	for _, l := range links {
+		g.addEdge(GraphEdge{From: l.SourceAppID, To: l.DatalakeID, Kind: EdgeFeeds, LinkID: l.ID})
+	}
+	sort.Slice(g.edges, func(i, j int) bool {
+		a, b := g.edges[i], g.edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.LinkID < b.LinkID
+	})
+	return g
+}
+
+// Graph returns the graph of the tree.
+func (t *HostingEnvironmentTree) Graph() *Graph {
+	return NewGraph([]HostingEnvironment{t.HostingEnvironment}, t.Datalakes, t.SourceApps, t.Links)
+}
+
+// GetGraph returns the graph of all hosting environments, datalakes, source
+// apps and links.
+func (c *Client) GetGraph(ctx context.Context, opts ...CallOption) (*Graph, error) {
+	inv, err := c.fetchInventory(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewGraph(inv.environments, inv.datalakes, inv.sourceApps, inv.links), nil
+}
+
+func (g *Graph) addEdge(e GraphEdge) {
+	if _, ok := g.nodes[e.From]; !ok {
+		return
+	}
+	if _, ok := g.nodes[e.To]; !ok {
+		return
+	}
+	g.edges = append(g.edges, e)
+	g.out[e.From] = append(g.out[e.From], e)
+	g.in[e.To] = append(g.in[e.To], e)
+}
+
+// Node returns the node with the given ID.
+func (g *Graph) Node(id string) (GraphNode, bool) {
+	n, ok := g.nodes[id]
+	return n, ok
+}
+
+// Nodes returns the nodes of the graph ordered by kind, name and ID.
+func (g *Graph) Nodes() []GraphNode {
+	nodes := make([]GraphNode, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// Edges returns the edges of the graph.
+func (g *Graph) Edges() []GraphEdge {
+	return append([]GraphEdge(nil), g.edges...)
+}
+
+// Reachable returns the nodes of the given kind that can be reached from the
+// node with the given ID by following edges forward. An empty kind returns
+// nodes of every kind.
+func (g *Graph) Reachable(id string, kind NodeKind) []GraphNode {
+	return g.walk(id, kind, g.out, func(e GraphEdge) string { return e.To })
+}
+
+// ReachableFrom returns the nodes of the given kind from which the node with
+// the given ID can be reached. An empty kind returns nodes of every kind.
+func (g *Graph) ReachableFrom(id string, kind NodeKind) []GraphNode {
+	return g.walk(id, kind, g.in, func(e GraphEdge) string { return e.From })
+}
+
+// DatalakesReachableFrom returns the datalakes the source app with the given
+// ID feeds.
+func (g *Graph) DatalakesReachableFrom(sourceAppID string) []GraphNode {
+	return g.Reachable(sourceAppID, NodeDatalake)
+}
+
+// SourceAppsFeeding returns the source apps that feed the datalake with the
+// given ID.
+func (g *Graph) SourceAppsFeeding(datalakeID string) []GraphNode {
+	return g.ReachableFrom(datalakeID, NodeSourceApp)
+}
+
+func (g *Graph) walk(id string, kind NodeKind, adjacent map[string][]GraphEdge, next func(GraphEdge) string) []GraphNode {
+	seen := map[string]bool{id: true}
+	queue := []string{id}
+	var nodes []GraphNode
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range adjacent[current] {
+			n := next(e)
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			queue = append(queue, n)
+			if node := g.nodes[n]; kind == "" || node.Kind == kind {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// MarshalJSON encodes the graph as its nodes and edges.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Nodes []GraphNode `json:"nodes"`
+		Edges []GraphEdge `json:"edges"`
+	}{g.Nodes(), g.Edges()})
+}
+
+// DOT returns the graph in the Graphviz DOT language. Hosting environments
+// are drawn as clusters around their datalakes and source apps.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph traceforce {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=filled];\n")
+
+	children := g.children()
+	for _, env := range g.nodesOfKind(NodeHostingEnvironment) {
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotID("cluster_"+env.ID))
+		fmt.Fprintf(&b, "    label=%s;\n", dotID(nodeLabel(env)))
+		fmt.Fprintf(&b, "    color=%s;\n", dotID(env.Color()))
+		for _, n := range children[env.ID] {
+			writeDOTNode(&b, "    ", n)
+		}
+		b.WriteString("  }\n")
+	}
+	for _, n := range children[""] {
+		writeDOTNode(&b, "  ", n)
+	}
+	for _, e := range g.edges {
+		if e.Kind == EdgeFeeds {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotID(e.From), dotID(e.To))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func writeDOTNode(b *strings.Builder, indent string, n GraphNode) {
+	shape := "box"
+	if n.Kind == NodeDatalake {
+		shape = "cylinder"
+	}
+	fmt.Fprintf(b, "%s%s [label=%s, shape=%s, fillcolor=%s];\n", indent, dotID(n.ID), dotID(nodeLabel(n)), shape, dotID(n.Color()))
+}
+
+// Mermaid returns the graph as a Mermaid flowchart. Hosting environments are
+// drawn as subgraphs around their datalakes and source apps.
+func (g *Graph) Mermaid() string {
+	// Mermaid IDs are limited, so nodes are numbered in the order of Nodes
+	ids := make(map[string]string, len(g.nodes))
+	for i, n := range g.Nodes() {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	children := g.children()
+	for _, env := range g.nodesOfKind(NodeHostingEnvironment) {
+		fmt.Fprintf(&b, "  subgraph %s[%s]\n", ids[env.ID], mermaidLabel(nodeLabel(env)))
+		for _, n := range children[env.ID] {
+			writeMermaidNode(&b, "    ", ids[n.ID], n)
+		}
+		b.WriteString("  end\n")
+	}
+	for _, n := range children[""] {
+		writeMermaidNode(&b, "  ", ids[n.ID], n)
+	}
+	for _, e := range g.edges {
+		if e.Kind == EdgeFeeds {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+		}
+	}
+	for _, n := range g.Nodes() {
+		fmt.Fprintf(&b, "  style %s fill:%s\n", ids[n.ID], n.Color())
+	}
+	return b.String()
+}
+
+func writeMermaidNode(b *strings.Builder, indent, id string, n GraphNode) {
+	if n.Kind == NodeDatalake {
+		fmt.Fprintf(b, "%s%s[(%s)]\n", indent, id, mermaidLabel(nodeLabel(n)))
+		return
+	}
+	fmt.Fprintf(b, "%s%s[%s]\n", indent, id, mermaidLabel(nodeLabel(n)))
+}
+
+// children returns the datalakes and source apps of every hosting
+// environment in the graph. Those of environments that are not in the graph
+// are returned under the empty ID.
+func (g *Graph) children() map[string][]GraphNode {
+	children := make(map[string][]GraphNode)
+	for _, n := range g.Nodes() {
+		if n.Kind == NodeHostingEnvironment {
+			continue
+		}
+		parent := n.HostingEnvironmentID
+		if _, ok := g.nodes[parent]; !ok {
+			parent = ""
+		}
+		children[parent] = append(children[parent], n)
+	}
+	return children
+}
+
+func (g *Graph) nodesOfKind(kind NodeKind) []GraphNode {
+	var nodes []GraphNode
+	for _, n := range g.Nodes() {
+		if n.Kind == kind {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+var nodeKindOrder = map[NodeKind]int{NodeHostingEnvironment: 0, NodeSourceApp: 1, NodeDatalake: 2}
+
+func sortNodes(nodes []GraphNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.Kind != b.Kind {
+			return nodeKindOrder[a.Kind] < nodeKindOrder[b.Kind]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+}
+
+// statusColor returns the colour of a resource status: green when it is
+// working, blue while it is being set up, yellow while pending, red when it
+// failed and grey when it is disconnected.
+func statusColor(status string) string {
+	switch status {
+	case string(HostingEnvironmentStatusConnected), string(DatalakeStatusReady):
+		return "#8fd18f"
+	case string(DatalakeStatusDeployed):
+		return "#8fb8e8"
+	case string(DatalakeStatusPending):
+		return "#f5d76e"
+	case string(DatalakeStatusFailed):
+		return "#f08080"
+	case string(HostingEnvironmentStatusDisconnected):
+		return "#c8c8c8"
+	}
+	return "#ffffff"
+}
+
+func nodeLabel(n GraphNode) string {
+	label := n.Name
+	if label == "" {
+		label = n.ID
+	}
+	if n.Status != "" {
+		label += " (" + n.Status + ")"
+	}
+	return label
+}
+
+func dotID(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func mermaidLabel(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s) + `"`
+}
+
+// inventory is every hosting environment, datalake, source app and link of
+// the tenant.
+type inventory struct {
+	environments []HostingEnvironment
+	datalakes    []Datalake
+	sourceApps   []SourceApp
+	links        []SourceAppDatalakeLink
+}
+
+// fetchInventory lists all resources concurrently.
+func (c *Client) fetchInventory(ctx context.Context, opts []CallOption) (*inventory, error) {
+	inv := &inventory{}
+	var (
+		wg   sync.WaitGroup
+		errs [4]error
+	)
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		inv.environments, errs[0] = c.GetHostingEnvironments(ctx, opts...)
+	}()
+	go func() {
+		defer wg.Done()
+		inv.datalakes, errs[1] = c.GetDatalakes(ctx, opts...)
+	}()
+	go func() {
+		defer wg.Done()
+		inv.sourceApps, errs[2] = c.GetSourceApps(ctx, opts...)
+	}()
+	go func() {
+		defer wg.Done()
+		inv.links, errs[3] = c.GetSourceAppDatalakeLinks(ctx, opts...)
+	}()
+	wg.Wait()
+	if err := errors.Join(errs[:]...); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
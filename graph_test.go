@@ -0,0 +1,82 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	prod := api.addEnvironment(HostingEnvironment{Name: "prod", Status: HostingEnvironmentStatusConnected})
+	staging := api.addEnvironment(HostingEnvironment{Name: "staging", Status: HostingEnvironmentStatusDisconnected})
+	ready := api.addDatalake(Datalake{HostingEnvironmentID: prod.ID, Name: "ready", Status: DatalakeStatusReady})
+	failed := api.addDatalake(Datalake{HostingEnvironmentID: staging.ID, Name: "failed", Status: DatalakeStatusFailed})
+	unlinked := api.addDatalake(Datalake{HostingEnvironmentID: prod.ID, Name: "unlinked", Status: DatalakeStatusPending})
+	app := api.addSourceApp(SourceApp{HostingEnvironmentID: prod.ID, Name: "sf", Status: SourceAppStatusConnected})
+	api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: ready.ID})
+	api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: failed.ID})
+	// Dangling links are left out
+	api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: "deleted"})
+
+	graph, err := client.GetGraph(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, graph.Nodes(), 6)
+	assert.Len(t, graph.Edges(), 6)
+
+	reachable := graph.DatalakesReachableFrom(app.ID)
+	if assert.Len(t, reachable, 2) {
+		assert.Equal(t, "failed", reachable[0].Name)
+		assert.Equal(t, "ready", reachable[1].Name)
+	}
+	assert.Len(t, graph.SourceAppsFeeding(failed.ID), 1)
+	assert.Empty(t, graph.SourceAppsFeeding(unlinked.ID))
+	// prod contains ready and unlinked and reaches failed through the source app
+	assert.Len(t, graph.Reachable(prod.ID, NodeDatalake), 3)
+	assert.Len(t, graph.ReachableFrom(failed.ID, ""), 3)
+
+	node, ok := graph.Node(failed.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "#f08080", node.Color())
+
+	dot := graph.DOT()
+	assert.True(t, strings.HasPrefix(dot, "digraph traceforce {"))
+	assert.Contains(t, dot, `subgraph "cluster_`+prod.ID+`"`)
+	assert.Contains(t, dot, `"`+app.ID+`" -> "`+ready.ID+`";`)
+	assert.Contains(t, dot, `label="ready (ready)", shape=cylinder, fillcolor="#8fd18f"`)
+
+	mermaid := graph.Mermaid()
+	assert.True(t, strings.HasPrefix(mermaid, "flowchart LR\n"))
+	assert.Contains(t, mermaid, `subgraph n0["prod (connected)"]`)
+	assert.Contains(t, mermaid, "n2 --> n")
+	assert.Contains(t, mermaid, "style n0 fill:#8fd18f")
+
+	data, err := json.Marshal(graph)
+	assert.NoError(t, err)
+	var decoded struct {
+		Nodes []GraphNode `json:"nodes"`
+		Edges []GraphEdge `json:"edges"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, graph.Nodes(), decoded.Nodes)
+	assert.Equal(t, graph.Edges(), decoded.Edges)
+}
+
+func TestTreeGraph(t *testing.T) {
+	tree := &HostingEnvironmentTree{
+		HostingEnvironment: HostingEnvironment{ID: "env", Name: "prod"},
+		Datalakes:          []Datalake{{ID: "lake", HostingEnvironmentID: "env", Name: "lake"}},
+		SourceApps:         []SourceApp{{ID: "app", HostingEnvironmentID: "env", Name: "app"}},
+		Links:              []SourceAppDatalakeLink{{ID: "link", SourceAppID: "app", DatalakeID: "lake"}},
+	}
+	graph := tree.Graph()
+	reachable := graph.DatalakesReachableFrom("app")
+	if assert.Len(t, reachable, 1) {
+		assert.Equal(t, "lake", reachable[0].ID)
+	}
+	assert.Contains(t, graph.Edges(), GraphEdge{From: "app", To: "lake", Kind: EdgeFeeds, LinkID: "link"})
+}
@@ -1,14 +1,14 @@
 package traceforce
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"reflect"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type HostingEnvironmentStatus string
@@ -21,11 +21,11 @@ const (
 
 // PostConnectionRequest represents the infrastructure configuration for post-connection setup
 type PostConnectionRequest struct {
-	Infrastructure           *Infrastructure `json:"infrastructure"`
-	TerraformURL             string          `json:"terraform_url"`
-	TerraformModuleVersions  string          `json:"terraform_module_versions"`     // JSON string
-	DeployedDatalakeIds      []string        `json:"deployed_datalake_ids"`
-	DeployedSourceAppIds     []string        `json:"deployed_source_app_ids"`
+	Infrastructure          *Infrastructure `json:"infrastructure"`
+	TerraformURL            string          `json:"terraform_url"`
+	TerraformModuleVersions string          `json:"terraform_module_versions"` // JSON string
+	DeployedDatalakeIds     []string        `json:"deployed_datalake_ids"`
+	DeployedSourceAppIds    []string        `json:"deployed_source_app_ids"`
 }
 
 // Infrastructure represents all connector-specific infrastructure outputs
@@ -37,11 +37,11 @@ type Infrastructure struct {
 
 // BaseInfrastructure represents base infrastructure outputs
 type BaseInfrastructure struct {
-	DataplaneIdentityIdentifier     string `json:"dataplane_identity_identifier"`
-	WorkloadIdentityProviderName    string `json:"workload_identity_provider_name"`
-	AuthViewGeneratorFunctionID     string `json:"auth_view_generator_function_id"`
-	AuthViewGeneratorFunctionURL    string `json:"auth_view_generator_function_url"`
-	TraceforceBucketName           string `json:"traceforce_bucket_name"`
+	DataplaneIdentityIdentifier  string `json:"dataplane_identity_identifier"`
+	WorkloadIdentityProviderName string `json:"workload_identity_provider_name"`
+	AuthViewGeneratorFunctionID  string `json:"auth_view_generator_function_id"`
+	AuthViewGeneratorFunctionURL string `json:"auth_view_generator_function_url"`
+	TraceforceBucketName         string `json:"traceforce_bucket_name"`
 }
 
 // BigQueryInfrastructure represents BigQuery datalake infrastructure outputs
@@ -53,15 +53,48 @@ type BigQueryInfrastructure struct {
 
 // SalesforceInfrastructure represents Salesforce source app infrastructure outputs
 type SalesforceInfrastructure struct {
+	ClientID     string `json:"salesforce_client_id"`
+	Domain       string `json:"salesforce_domain"`
+	ClientSecret Secret `json:"salesforce_client_secret"`
+}
+
+// wireInfrastructure mirrors Infrastructure with every Secret revealed. It is
+// only used to build request bodies and must never be logged.
+type wireInfrastructure struct {
+	Base       *BaseInfrastructure           `json:"base,omitempty"`
+	BigQuery   *BigQueryInfrastructure       `json:"bigquery,omitempty"`
+	Salesforce *wireSalesforceInfrastructure `json:"salesforce,omitempty"`
+}
+
+type wireSalesforceInfrastructure struct {
 	ClientID     string `json:"salesforce_client_id"`
 	Domain       string `json:"salesforce_domain"`
 	ClientSecret string `json:"salesforce_client_secret"`
 }
 
+// wire returns the request body representation of the infrastructure.
+func (i *Infrastructure) wire() *wireInfrastructure {
+	if i == nil {
+		return nil
+	}
+	w := &wireInfrastructure{
+		Base:     i.Base,
+		BigQuery: i.BigQuery,
+	}
+	if i.Salesforce != nil {
+		w.Salesforce = &wireSalesforceInfrastructure{
+			ClientID:     i.Salesforce.ClientID,
+			Domain:       i.Salesforce.Domain,
+			ClientSecret: i.Salesforce.ClientSecret.Reveal(),
+		}
+	}
+	return w
+}
+
 type HostingEnvironmentType string
 
 const (
-	HostingEnvironmentTypeCustomerManaged  HostingEnvironmentType = "customer_managed"
+	HostingEnvironmentTypeCustomerManaged   HostingEnvironmentType = "customer_managed"
 	HostingEnvironmentTypeTraceForceManaged HostingEnvironmentType = "traceforce_managed"
 )
 
@@ -75,14 +108,20 @@ const (
 
 // Request types
 type CreateHostingEnvironmentRequest struct {
-	Name          string                   `json:"name"`
-	Type          HostingEnvironmentType   `json:"type"`
-	CloudProvider CloudProvider            `json:"cloud_provider"`
-	NativeID      string                   `json:"native_id"`
+	Name          string                 `json:"name"`
+	Type          HostingEnvironmentType `json:"type"`
+	CloudProvider CloudProvider          `json:"cloud_provider"`
+	NativeID      string                 `json:"native_id"`
+	Labels        map[string]string      `json:"labels,omitempty"`
+	Annotations   map[string]string      `json:"annotations,omitempty"`
 }
 
+// UpdateHostingEnvironmentRequest changes the fields that are set and leaves the others
+// unchanged. Optional fields are cleared when set to Null.
 type UpdateHostingEnvironmentRequest struct {
-	Name *string `json:"name,omitempty"`
+	Name        *string                     `json:"name,omitempty"`
+	Labels      Optional[map[string]string] `json:"labels,omitzero"`
+	Annotations Optional[map[string]string] `json:"annotations,omitzero"`
 }
 
 // Response type
@@ -93,38 +132,48 @@ type HostingEnvironment struct {
 	CloudProvider CloudProvider            `json:"cloud_provider"`
 	NativeID      string                   `json:"native_id"`
 	Status        HostingEnvironmentStatus `json:"status"`
+	Labels        map[string]string        `json:"labels,omitempty"`
+	Annotations   map[string]string        `json:"annotations,omitempty"`
 	CreatedAt     time.Time                `json:"created_at"`
 	UpdatedAt     time.Time                `json:"updated_at"`
+
+	// ETag identifies the version of the resource for WithIfMatch. It is
+	// only set on resources returned individually.
+	ETag string `json:"-"`
 }
 
-func (c *Client) CreateHostingEnvironment(req CreateHostingEnvironmentRequest) (*HostingEnvironment, error) {
-	url := c.baseURL + "/hosting-environments"
-	headers := c.buildHeaders()
+func (e *HostingEnvironment) setETag(etag string) {
+	e.ETag = etag
+}
 
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
-	}
+func (e HostingEnvironment) labelSet() map[string]string {
+	return e.Labels
+}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { httpReq.Header.Set(k, v) }
-	httpReq.Header.Set("Content-Type", "application/json")
+func (e HostingEnvironment) resourceID() string {
+	return e.ID
+}
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (e *HostingEnvironment) clone() *HostingEnvironment {
+	c := *e
+	c.Labels = cloneMap(e.Labels)
+	c.Annotations = cloneMap(e.Annotations)
+	return &c
+}
 
-	if err := validateResponse(resp); err != nil {
+func (c *Client) CreateHostingEnvironment(ctx context.Context, req CreateHostingEnvironmentRequest, opts ...CallOption) (*HostingEnvironment, error) {
+	if err := validateLabelsAndAnnotations(req.Labels, req.Annotations); err != nil {
 		return nil, err
 	}
 
 	var createdEnv HostingEnvironment
-	err = json.NewDecoder(resp.Body).Decode(&createdEnv)
+	err := c.call(ctx, apiRequest{
+		operation: "CreateHostingEnvironment",
+		method:    "POST",
+		path:      "/hosting-environments",
+		body:      req,
+		opts:      opts,
+	}, &createdEnv)
 	if err != nil {
 		return nil, err
 	}
@@ -132,36 +181,22 @@ func (c *Client) CreateHostingEnvironment(req CreateHostingEnvironmentRequest) (
 	return &createdEnv, nil
 }
 
-func (c *Client) GetHostingEnvironments() ([]HostingEnvironment, error) {
-	url := c.baseURL + "/hosting-environments"
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
+func (c *Client) GetHostingEnvironments(ctx context.Context, opts ...CallOption) ([]HostingEnvironment, error) {
 	var environments []HostingEnvironment
-	err = json.NewDecoder(resp.Body).Decode(&environments)
+	err := c.call(ctx, apiRequest{
+		operation: "GetHostingEnvironments",
+		method:    "GET",
+		path:      "/hosting-environments",
+		opts:      opts,
+	}, &environments)
 	if err != nil {
 		return nil, err
 	}
 
-	return environments, nil
+	return filterByLabels(environments, newCallOptions(opts).labelSelector), nil
 }
 
-
-func (c *Client) GetHostingEnvironment(id string) (*HostingEnvironment, error) {
+func (c *Client) GetHostingEnvironment(ctx context.Context, id string, opts ...CallOption) (*HostingEnvironment, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id cannot be empty")
 	}
@@ -172,26 +207,14 @@ func (c *Client) GetHostingEnvironment(id string) (*HostingEnvironment, error) {
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/hosting-environments/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var environment HostingEnvironment
-	err = json.NewDecoder(resp.Body).Decode(&environment)
+	err = c.call(ctx, apiRequest{
+		operation: "GetHostingEnvironment",
+		method:    "GET",
+		path:      "/hosting-environments/" + id,
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(id)},
+		opts:      opts,
+	}, &environment)
 	if err != nil {
 		return nil, err
 	}
@@ -199,7 +222,17 @@ func (c *Client) GetHostingEnvironment(id string) (*HostingEnvironment, error) {
 	return &environment, nil
 }
 
-func (c *Client) UpdateHostingEnvironment(id string, req UpdateHostingEnvironmentRequest) (*HostingEnvironment, error) {
+// GetHostingEnvironmentsByIDs fetches the hosting environments with the given
+// IDs concurrently, with at most ClientOptions.BatchConcurrency requests in
+// flight. IDs that could not be fetched are reported in the result's Errors
+// instead of failing the batch.
+func (c *Client) GetHostingEnvironmentsByIDs(ctx context.Context, ids []string, opts ...CallOption) *BatchResult[HostingEnvironment] {
+	return fetchBatch(ctx, c, ids, func(ctx context.Context, id string) (*HostingEnvironment, error) {
+		return c.GetHostingEnvironment(ctx, id, opts...)
+	})
+}
+
+func (c *Client) UpdateHostingEnvironment(ctx context.Context, id string, req UpdateHostingEnvironmentRequest, opts ...CallOption) (*HostingEnvironment, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id cannot be empty")
 	}
@@ -210,41 +243,79 @@ func (c *Client) UpdateHostingEnvironment(id string, req UpdateHostingEnvironmen
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/hosting-environments/" + id
-	headers := c.buildHeaders()
-
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
+	labels, _ := req.Labels.Get()
+	annotations, _ := req.Annotations.Get()
+	if err := validateLabelsAndAnnotations(labels, annotations); err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonBody))
+	var updatedEnv HostingEnvironment
+	err = c.call(ctx, apiRequest{
+		operation: "UpdateHostingEnvironment",
+		method:    "PATCH",
+		path:      "/hosting-environments/" + id,
+		body:      req,
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(id)},
+		opts:      opts,
+	}, &updatedEnv)
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range headers { httpReq.Header.Set(k, v) }
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return &updatedEnv, nil
+}
 
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
+// UpdateHostingEnvironmentWithRetry reads the hosting environment, applies mutate to it and saves the
+// changes, conditional on the hosting environment not having changed in between. If it
+// has, the cycle starts over with the new version, up to a few times, and an
+// idempotency key set with WithIdempotencyKey gets an attempt suffix. Only
+// fields accepted by UpdateHostingEnvironment may be changed.
+func (c *Client) UpdateHostingEnvironmentWithRetry(ctx context.Context, id string, mutate func(*HostingEnvironment) error, opts ...CallOption) (*HostingEnvironment, error) {
+	return updateWithRetry(ctx,
+		func(ctx context.Context) (*HostingEnvironment, error) {
+			return c.GetHostingEnvironment(ctx, id, appendOptions(opts, noCache())...)
+		},
+		mutate,
+		func(ctx context.Context, attempt int, current, updated *HostingEnvironment) (*HostingEnvironment, error) {
+			req, changed, err := hostingEnvironmentUpdate(current, updated)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				return current, nil
+			}
+			return c.UpdateHostingEnvironment(ctx, id, req, appendOptions(attemptOptions(opts, attempt), preconditionFor(current.ETag, current.UpdatedAt))...)
+		})
+}
 
-	var updatedEnv HostingEnvironment
-	err = json.NewDecoder(resp.Body).Decode(&updatedEnv)
-	if err != nil {
-		return nil, err
+// hostingEnvironmentUpdate returns the request that turns current into updated.
+func hostingEnvironmentUpdate(current, updated *HostingEnvironment) (UpdateHostingEnvironmentRequest, bool, error) {
+	var req UpdateHostingEnvironmentRequest
+	changed := false
+	if updated.Name != current.Name {
+		req.Name = &updated.Name
+		changed = true
+	}
+	if !mapsEqual(updated.Labels, current.Labels) {
+		req.Labels = optionalMap(updated.Labels)
+		changed = true
+	}
+	if !mapsEqual(updated.Annotations, current.Annotations) {
+		req.Annotations = optionalMap(updated.Annotations)
+		changed = true
 	}
 
-	return &updatedEnv, nil
+	rest := *updated
+	rest.Name = current.Name
+	rest.Labels = current.Labels
+	rest.Annotations = current.Annotations
+	if !reflect.DeepEqual(&rest, current) {
+		return req, false, fmt.Errorf("only the name, labels and annotations of a hosting environment can be updated")
+	}
+	return req, changed, nil
 }
 
-func (c *Client) DeleteHostingEnvironment(id string) error {
+func (c *Client) DeleteHostingEnvironment(ctx context.Context, id string, opts ...CallOption) error {
 	if id == "" {
 		return fmt.Errorf("id cannot be empty")
 	}
@@ -255,28 +326,16 @@ func (c *Client) DeleteHostingEnvironment(id string) error {
 		return fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/hosting-environments/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return err
-	}
-
-	return nil
+	return c.call(ctx, apiRequest{
+		operation: "DeleteHostingEnvironment",
+		method:    "DELETE",
+		path:      "/hosting-environments/" + id,
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(id)},
+		opts:      opts,
+	}, nil)
 }
 
-func (c *Client) PostConnection(id string, req *PostConnectionRequest) error {
+func (c *Client) PostConnection(ctx context.Context, id string, req *PostConnectionRequest, opts ...CallOption) error {
 	if id == "" {
 		return fmt.Errorf("id cannot be empty")
 	}
@@ -296,22 +355,26 @@ func (c *Client) PostConnection(id string, req *PostConnectionRequest) error {
 	if req.TerraformModuleVersions == "" {
 		return fmt.Errorf("terraform_module_versions cannot be empty")
 	}
-	
+
 	var terraformModuleVersions interface{}
 	if err := json.Unmarshal([]byte(req.TerraformModuleVersions), &terraformModuleVersions); err != nil {
 		return fmt.Errorf("invalid terraform_module_versions JSON: %v", err)
 	}
 
-	url := c.baseURL + "/hosting-environments/" + id + "/post-connection"
-	headers := c.buildHeaders()
+	// Resolve secret references only now, so they are held in memory for as
+	// short a time as possible
+	infrastructure, err := c.resolveInfrastructureSecrets(ctx, req.Infrastructure)
+	if err != nil {
+		return err
+	}
 
 	// Create request payload with infrastructure configuration and terraform metadata
 	payload := map[string]interface{}{
-		"infrastructure":            req.Infrastructure,
+		"infrastructure":            infrastructure.wire(),
 		"terraform_url":             req.TerraformURL,
 		"terraform_module_versions": terraformModuleVersions,
 		"deployed_datalake_ids":     req.DeployedDatalakeIds,
-		"deployed_source_app_ids":    req.DeployedSourceAppIds,
+		"deployed_source_app_ids":   req.DeployedSourceAppIds,
 	}
 
 	jsonPayload, err := json.Marshal(payload)
@@ -319,35 +382,12 @@ func (c *Client) PostConnection(id string, req *PostConnectionRequest) error {
 		return fmt.Errorf("failed to marshal infrastructure configuration: %v", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return err
-	}
-	for k, v := range headers {
-		httpReq.Header.Set(k, v)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return err
-	}
-
-	return nil
+	return c.call(ctx, apiRequest{
+		operation: "PostConnection",
+		method:    "POST",
+		path:      "/hosting-environments/" + id + "/post-connection",
+		body:      json.RawMessage(jsonPayload),
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(id)},
+		opts:      opts,
+	}, nil)
 }
-
-func validateResponse(resp *http.Response) error {
-	if resp.StatusCode >= 400 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read error response body: %v", err)
-		}
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
-	}
-	return nil
-}
\ No newline at end of file
@@ -1,6 +1,7 @@
 package traceforce
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -12,6 +13,7 @@ func TestHostingEnvironments(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	testEnvironmentName := "test hosting environment"
 	environmentReq := CreateHostingEnvironmentRequest{
@@ -21,7 +23,7 @@ func TestHostingEnvironments(t *testing.T) {
 		NativeID:      "123456789012",
 	}
 
-	createdEnvironment, err := client.CreateHostingEnvironment(environmentReq)
+	createdEnvironment, err := client.CreateHostingEnvironment(ctx, environmentReq)
 	if err != nil {
 		t.Fatalf("Failed to create hosting environment: %v", err)
 	}
@@ -34,7 +36,7 @@ func TestHostingEnvironments(t *testing.T) {
 	assert.Equal(t, environmentReq.NativeID, createdEnvironment.NativeID)
 	assert.Equal(t, HostingEnvironmentStatusPending, createdEnvironment.Status)
 
-	environments, err := client.GetHostingEnvironments()
+	environments, err := client.GetHostingEnvironments(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get hosting environments: %v", err)
 	}
@@ -43,7 +45,6 @@ func TestHostingEnvironments(t *testing.T) {
 	assert.NotNil(t, environments)
 	assert.NotEmpty(t, environments)
 
-	var testEnvironment HostingEnvironment
 	for _, env := range environments {
 		t.Logf("Hosting environment: %+v", env)
 		assert.NotNil(t, env.ID)
@@ -51,16 +52,18 @@ func TestHostingEnvironments(t *testing.T) {
 		assert.NotEmpty(t, env.Type)
 		assert.NotEmpty(t, env.NativeID)
 		assert.NotEmpty(t, env.Status)
+	}
 
-		if env.Name == testEnvironmentName {
-			testEnvironment = env
-		}
+	matches, err := client.FindHostingEnvironmentsByName(ctx, testEnvironmentName)
+	if err != nil {
+		t.Fatalf("Failed to find hosting environment by name: %v", err)
 	}
+	testEnvironment := matches[len(matches)-1]
 
 	t.Logf("Test hosting environment: %+v", testEnvironment)
 	assert.NotNil(t, testEnvironment)
 
-	environmentByID, err := client.GetHostingEnvironment(testEnvironment.ID)
+	environmentByID, err := client.GetHostingEnvironment(ctx, testEnvironment.ID)
 	if err != nil {
 		t.Fatalf("Failed to get hosting environment by ID: %v", err)
 	}
@@ -73,7 +76,7 @@ func TestHostingEnvironments(t *testing.T) {
 	updateReq := UpdateHostingEnvironmentRequest{
 		Name: &newName,
 	}
-	updatedEnvironment, err := client.UpdateHostingEnvironment(testEnvironment.ID, updateReq)
+	updatedEnvironment, err := client.UpdateHostingEnvironment(ctx, testEnvironment.ID, updateReq)
 	if err != nil {
 		t.Fatalf("Failed to update hosting environment: %v", err)
 	}
@@ -83,7 +86,7 @@ func TestHostingEnvironments(t *testing.T) {
 	assert.Equal(t, newName, updatedEnvironment.Name)
 	// Note: Status update is not supported via UpdateHostingEnvironmentRequest
 
-	err = client.DeleteHostingEnvironment(testEnvironment.ID)
+	err = client.DeleteHostingEnvironment(ctx, testEnvironment.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete hosting environment: %v", err)
 	}
@@ -96,36 +99,37 @@ func TestHostingEnvironmentValidation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	// Test GetHostingEnvironment with empty ID
-	_, err = client.GetHostingEnvironment("")
+	_, err = client.GetHostingEnvironment(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test GetHostingEnvironment with invalid UUID
-	_, err = client.GetHostingEnvironment("invalid-uuid")
+	_, err = client.GetHostingEnvironment(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test UpdateHostingEnvironment with empty ID
 	testName := "test"
 	updateReq := UpdateHostingEnvironmentRequest{Name: &testName}
-	_, err = client.UpdateHostingEnvironment("", updateReq)
+	_, err = client.UpdateHostingEnvironment(ctx, "", updateReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test UpdateHostingEnvironment with invalid UUID
-	_, err = client.UpdateHostingEnvironment("invalid-uuid", updateReq)
+	_, err = client.UpdateHostingEnvironment(ctx, "invalid-uuid", updateReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test DeleteHostingEnvironment with empty ID
-	err = client.DeleteHostingEnvironment("")
+	err = client.DeleteHostingEnvironment(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test DeleteHostingEnvironment with invalid UUID
-	err = client.DeleteHostingEnvironment("invalid-uuid")
+	err = client.DeleteHostingEnvironment(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 }
@@ -135,6 +139,7 @@ func TestPostConnection(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	testEnvironmentName := "test hosting environment for post connection"
 	environmentReq := CreateHostingEnvironmentRequest{
@@ -145,12 +150,12 @@ func TestPostConnection(t *testing.T) {
 	}
 
 	// Create hosting environment
-	createdEnvironment, err := client.CreateHostingEnvironment(environmentReq)
+	createdEnvironment, err := client.CreateHostingEnvironment(ctx, environmentReq)
 	if err != nil {
 		t.Fatalf("Failed to create hosting environment: %v", err)
 	}
 	defer func() {
-		err := client.DeleteHostingEnvironment(createdEnvironment.ID)
+		err := client.DeleteHostingEnvironment(ctx, createdEnvironment.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup hosting environment: %v", err)
 		}
@@ -175,7 +180,7 @@ func TestPostConnection(t *testing.T) {
 		DeployedDatalakeIds:     []string{"datalake-1", "datalake-2"},
 		DeployedSourceAppIds:    []string{"source-app-1", "source-app-2"},
 	}
-	err = client.PostConnection(createdEnvironment.ID, postConnReq)
+	err = client.PostConnection(ctx, createdEnvironment.ID, postConnReq)
 	if err != nil {
 		t.Fatalf("Failed to execute post-connection: %v", err)
 	}
@@ -186,6 +191,7 @@ func TestPostConnectionWithBigQuery(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	testEnvironmentName := "test hosting environment for post connection with bigquery and salesforce"
 	environmentReq := CreateHostingEnvironmentRequest{
@@ -196,12 +202,12 @@ func TestPostConnectionWithBigQuery(t *testing.T) {
 	}
 
 	// Create hosting environment
-	createdEnvironment, err := client.CreateHostingEnvironment(environmentReq)
+	createdEnvironment, err := client.CreateHostingEnvironment(ctx, environmentReq)
 	if err != nil {
 		t.Fatalf("Failed to create hosting environment: %v", err)
 	}
 	defer func() {
-		err := client.DeleteHostingEnvironment(createdEnvironment.ID)
+		err := client.DeleteHostingEnvironment(ctx, createdEnvironment.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup hosting environment: %v", err)
 		}
@@ -225,7 +231,7 @@ func TestPostConnectionWithBigQuery(t *testing.T) {
 			Salesforce: &SalesforceInfrastructure{
 				ClientID:     "test_client_id_123",
 				Domain:       "test-company.my.salesforce.com",
-				ClientSecret: "projects/test/secrets/salesforce-secret/versions/latest",
+				ClientSecret: NewSecret("projects/test/secrets/salesforce-secret/versions/latest"),
 			},
 		},
 		TerraformURL:            "https://github.com/traceforce/terraform-modules",
@@ -233,7 +239,7 @@ func TestPostConnectionWithBigQuery(t *testing.T) {
 		DeployedDatalakeIds:     []string{"datalake-1"},
 		DeployedSourceAppIds:    []string{"source-app-1"},
 	}
-	err = client.PostConnection(createdEnvironment.ID, postConnReq)
+	err = client.PostConnection(ctx, createdEnvironment.ID, postConnReq)
 	if err != nil {
 		t.Fatalf("Failed to execute post-connection with BigQuery and Salesforce: %v", err)
 	}
@@ -244,6 +250,7 @@ func TestPostConnectionValidation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	// Create a valid request for ID validation tests
 	validReq := &PostConnectionRequest{
@@ -253,18 +260,18 @@ func TestPostConnectionValidation(t *testing.T) {
 	}
 
 	// Test PostConnection with empty ID
-	err = client.PostConnection("", validReq)
+	err = client.PostConnection(ctx, "", validReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test PostConnection with invalid UUID
-	err = client.PostConnection("invalid-uuid", validReq)
+	err = client.PostConnection(ctx, "invalid-uuid", validReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test PostConnection with nil request
 	validUUID := "550e8400-e29b-41d4-a716-446655440000"
-	err = client.PostConnection(validUUID, nil)
+	err = client.PostConnection(ctx, validUUID, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "request cannot be nil")
 
@@ -274,7 +281,7 @@ func TestPostConnectionValidation(t *testing.T) {
 		DeployedDatalakeIds:     []string{},
 		DeployedSourceAppIds:    []string{},
 	}
-	err = client.PostConnection(validUUID, emptyReq)
+	err = client.PostConnection(ctx, validUUID, emptyReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "terraform_module_versions cannot be empty")
 
@@ -284,7 +291,7 @@ func TestPostConnectionValidation(t *testing.T) {
 		DeployedDatalakeIds:     []string{},
 		DeployedSourceAppIds:    []string{},
 	}
-	err = client.PostConnection(validUUID, invalidJSONReq)
+	err = client.PostConnection(ctx, validUUID, invalidJSONReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid terraform_module_versions JSON")
 
@@ -295,7 +302,7 @@ func TestPostConnectionValidation(t *testing.T) {
 		DeployedDatalakeIds:     []string{},
 		DeployedSourceAppIds:    []string{},
 	}
-	err = client.PostConnection(validUUID, validJSONReq)
+	err = client.PostConnection(ctx, validUUID, validJSONReq)
 	// This should fail with HTTP error, not JSON parsing error
 	assert.Error(t, err)
 	assert.NotContains(t, err.Error(), "invalid terraform_module_versions JSON")
@@ -0,0 +1,424 @@
+package traceforce
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Informer is a local cache of hosting environments, datalakes, source apps
+// and links fed by a Watcher. Its listers serve reads from memory, and its
+// event handlers are called for every change. It is safe for concurrent use,
+// so one informer can serve a whole process.
+//
+//	informer := client.NewInformer(traceforce.WatchOptions{Interval: time.Minute})
+//	go informer.Run(ctx)
+//	if err := informer.WaitForCacheSync(ctx); err != nil {
+//		return err
+//	}
+//	datalakes := informer.Datalakes().ByHostingEnvironment(envID)
+type Informer struct {
+	client  *Client
+	options WatchOptions
+	opts    []CallOption
+
+	mu           sync.RWMutex
+	environments *indexedStore[HostingEnvironment]
+	datalakes    *indexedStore[Datalake]
+	sourceApps   *indexedStore[SourceApp]
+	links        *indexedStore[SourceAppDatalakeLink]
+	synced       bool
+
+	// handlersMu is held while events are applied and handled, so handlers
+	// added later see every event exactly once.
+	handlersMu sync.Mutex
+	handlers   []func(Event)
+
+	// syncedCh is closed once the cache is synced, stopped once Run returns.
+	syncedCh chan struct{}
+	stopped  chan struct{}
+	runErr   error
+}
+
+// Index names of the stores.
+const (
+	indexHostingEnvironment = "hosting_environment"
+	indexSourceApp          = "source_app"
+	indexDatalake           = "datalake"
+)
+
+// NewInformer returns an informer that watches with the given options once
+// Run is called. options.Snapshot is ignored, since the cache starts empty.
+func (c *Client) NewInformer(options WatchOptions, opts ...CallOption) *Informer {
+	options.Snapshot = nil
+	return &Informer{
+		client:  c,
+		options: options,
+		opts:    opts,
+		environments: newIndexedStore(func(e HostingEnvironment) string { return e.ID },
+			map[string]func(HostingEnvironment) string{}),
+		datalakes: newIndexedStore(func(d Datalake) string { return d.ID },
+			map[string]func(Datalake) string{
+				indexHostingEnvironment: func(d Datalake) string { return d.HostingEnvironmentID },
+			}),
+		sourceApps: newIndexedStore(func(s SourceApp) string { return s.ID },
+			map[string]func(SourceApp) string{
+				indexHostingEnvironment: func(s SourceApp) string { return s.HostingEnvironmentID },
+			}),
+		links: newIndexedStore(func(l SourceAppDatalakeLink) string { return l.ID },
+			map[string]func(SourceAppDatalakeLink) string{
+				indexHostingEnvironment: func(l SourceAppDatalakeLink) string { return l.HostingEnvironmentID },
+				indexSourceApp:          func(l SourceAppDatalakeLink) string { return l.SourceAppID },
+				indexDatalake:           func(l SourceAppDatalakeLink) string { return l.DatalakeID },
+			}),
+		syncedCh: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Run fills the cache and keeps it up to date until ctx is done. It returns
+// an error if the first poll fails. Run must be called only once.
+func (i *Informer) Run(ctx context.Context) error {
+	defer close(i.stopped)
+	watcher, err := i.client.watch(ctx, i.options, i.opts, i.apply)
+	if err != nil {
+		i.runErr = err
+		return err
+	}
+	<-ctx.Done()
+	watcher.Stop()
+	return nil
+}
+
+// apply updates the cache with the events of one poll and calls the
+// handlers.
+func (i *Informer) apply(ctx context.Context, events []Event) bool {
+	i.handlersMu.Lock()
+	defer i.handlersMu.Unlock()
+
+	i.mu.Lock()
+	for _, event := range events {
+		i.applyEvent(event)
+	}
+	firstSync := !i.synced
+	i.synced = true
+	i.mu.Unlock()
+	if firstSync {
+		close(i.syncedCh)
+	}
+
+	for _, event := range events {
+		if ctx.Err() != nil {
+			return false
+		}
+		for _, handler := range i.handlers {
+			handler(event)
+		}
+	}
+	return true
+}
+
+func (i *Informer) applyEvent(event Event) {
+	deleted := event.Type == EventDeleted
+	switch v := event.Object().(type) {
+	case HostingEnvironment:
+		i.environments.apply(v, deleted)
+	case Datalake:
+		i.datalakes.apply(v, deleted)
+	case SourceApp:
+		i.sourceApps.apply(v, deleted)
+	case SourceAppDatalakeLink:
+		i.links.apply(v, deleted)
+	}
+}
+
+// AddEventHandler calls handler for every change to the cache, after the
+// cache was updated. If the cache is already synced, handler is first called
+// with an EventAdded for every cached resource. Handlers are called one at a
+// time and must not add handlers themselves.
+func (i *Informer) AddEventHandler(handler func(Event)) {
+	i.handlersMu.Lock()
+	defer i.handlersMu.Unlock()
+
+	i.mu.RLock()
+	var existing []Event
+	if i.synced {
+		for _, e := range i.environments.list() {
+			existing = append(existing, Event{Type: EventAdded, Kind: "hosting_environment", ID: e.ID, New: e})
+		}
+		for _, d := range i.datalakes.list() {
+			existing = append(existing, Event{Type: EventAdded, Kind: "datalake", ID: d.ID, New: d})
+		}
+		for _, s := range i.sourceApps.list() {
+			existing = append(existing, Event{Type: EventAdded, Kind: "source_app", ID: s.ID, New: s})
+		}
+		for _, l := range i.links.list() {
+			existing = append(existing, Event{Type: EventAdded, Kind: "source_app_datalake_link", ID: l.ID, New: l})
+		}
+	}
+	i.mu.RUnlock()
+
+	for _, event := range existing {
+		handler(event)
+	}
+	i.handlers = append(i.handlers, handler)
+}
+
+// HasSynced reports whether the cache holds the result of the first poll.
+func (i *Informer) HasSynced() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.synced
+}
+
+// WaitForCacheSync waits until the cache is synced. It returns an error if
+// ctx is done or Run failed first.
+func (i *Informer) WaitForCacheSync(ctx context.Context) error {
+	select {
+	case <-i.syncedCh:
+		return nil
+	case <-i.stopped:
+		if i.runErr != nil {
+			return fmt.Errorf("informer failed to sync: %w", i.runErr)
+		}
+		return fmt.Errorf("informer stopped before syncing")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HostingEnvironments returns a lister of the cached hosting environments.
+func (i *Informer) HostingEnvironments() HostingEnvironmentLister {
+	return HostingEnvironmentLister{i}
+}
+
+// Datalakes returns a lister of the cached datalakes.
+func (i *Informer) Datalakes() DatalakeLister {
+	return DatalakeLister{i}
+}
+
+// SourceApps returns a lister of the cached source apps.
+func (i *Informer) SourceApps() SourceAppLister {
+	return SourceAppLister{i}
+}
+
+// Links returns a lister of the cached source app datalake links.
+func (i *Informer) Links() LinkLister {
+	return LinkLister{i}
+}
+
+// The listers return copies of the cached resources ordered by ID. Get
+// returns a *NotFoundError for resources that are not cached.
+
+// HostingEnvironmentLister reads hosting environments from an Informer.
+type HostingEnvironmentLister struct {
+	i *Informer
+}
+
+func (l HostingEnvironmentLister) Get(id string) (*HostingEnvironment, error) {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	e, ok := l.i.environments.get(id)
+	if !ok {
+		return nil, &NotFoundError{Kind: "hosting environment", Query: fmt.Sprintf("ID %q", id)}
+	}
+	return e.clone(), nil
+}
+
+func (l HostingEnvironmentLister) List() []HostingEnvironment {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.environments.list(), (*HostingEnvironment).clone)
+}
+
+// DatalakeLister reads datalakes from an Informer.
+type DatalakeLister struct {
+	i *Informer
+}
+
+func (l DatalakeLister) Get(id string) (*Datalake, error) {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	d, ok := l.i.datalakes.get(id)
+	if !ok {
+		return nil, &NotFoundError{Kind: "datalake", Query: fmt.Sprintf("ID %q", id)}
+	}
+	return d.clone(), nil
+}
+
+func (l DatalakeLister) List() []Datalake {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.datalakes.list(), (*Datalake).clone)
+}
+
+// ByHostingEnvironment returns the datalakes of a hosting environment.
+func (l DatalakeLister) ByHostingEnvironment(hostingEnvironmentID string) []Datalake {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.datalakes.byIndex(indexHostingEnvironment, hostingEnvironmentID), (*Datalake).clone)
+}
+
+// SourceAppLister reads source apps from an Informer.
+type SourceAppLister struct {
+	i *Informer
+}
+
+func (l SourceAppLister) Get(id string) (*SourceApp, error) {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	s, ok := l.i.sourceApps.get(id)
+	if !ok {
+		return nil, &NotFoundError{Kind: "source app", Query: fmt.Sprintf("ID %q", id)}
+	}
+	return s.clone(), nil
+}
+
+func (l SourceAppLister) List() []SourceApp {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.sourceApps.list(), (*SourceApp).clone)
+}
+
+// ByHostingEnvironment returns the source apps of a hosting environment.
+func (l SourceAppLister) ByHostingEnvironment(hostingEnvironmentID string) []SourceApp {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.sourceApps.byIndex(indexHostingEnvironment, hostingEnvironmentID), (*SourceApp).clone)
+}
+
+// LinkLister reads source app datalake links from an Informer.
+type LinkLister struct {
+	i *Informer
+}
+
+func (l LinkLister) Get(id string) (*SourceAppDatalakeLink, error) {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	link, ok := l.i.links.get(id)
+	if !ok {
+		return nil, &NotFoundError{Kind: "source app datalake link", Query: fmt.Sprintf("ID %q", id)}
+	}
+	return cloneLink(&link), nil
+}
+
+func (l LinkLister) List() []SourceAppDatalakeLink {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.links.list(), cloneLink)
+}
+
+// ByHostingEnvironment returns the links of a hosting environment.
+func (l LinkLister) ByHostingEnvironment(hostingEnvironmentID string) []SourceAppDatalakeLink {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.links.byIndex(indexHostingEnvironment, hostingEnvironmentID), cloneLink)
+}
+
+// BySourceApp returns the links of a source app.
+func (l LinkLister) BySourceApp(sourceAppID string) []SourceAppDatalakeLink {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.links.byIndex(indexSourceApp, sourceAppID), cloneLink)
+}
+
+// ByDatalake returns the links to a datalake.
+func (l LinkLister) ByDatalake(datalakeID string) []SourceAppDatalakeLink {
+	l.i.mu.RLock()
+	defer l.i.mu.RUnlock()
+	return cloneAll(l.i.links.byIndex(indexDatalake, datalakeID), cloneLink)
+}
+
+func cloneLink(l *SourceAppDatalakeLink) *SourceAppDatalakeLink {
+	c := *l
+	c.Labels = cloneMap(l.Labels)
+	c.Annotations = cloneMap(l.Annotations)
+	return &c
+}
+
+func cloneAll[T any](items []T, clone func(*T) *T) []T {
+	cloned := make([]T, len(items))
+	for i := range items {
+		cloned[i] = *clone(&items[i])
+	}
+	return cloned
+}
+
+// indexedStore holds resources by ID and maintains indexes of them. It is
+// not safe for concurrent use.
+type indexedStore[T any] struct {
+	id       func(T) string
+	items    map[string]T
+	indexers map[string]func(T) string
+	// indexes maps index names to index keys to IDs
+	indexes map[string]map[string]map[string]bool
+}
+
+func newIndexedStore[T any](id func(T) string, indexers map[string]func(T) string) *indexedStore[T] {
+	s := &indexedStore[T]{
+		id:       id,
+		items:    make(map[string]T),
+		indexers: indexers,
+		indexes:  make(map[string]map[string]map[string]bool),
+	}
+	for name := range indexers {
+		s.indexes[name] = make(map[string]map[string]bool)
+	}
+	return s
+}
+
+func (s *indexedStore[T]) apply(item T, deleted bool) {
+	id := s.id(item)
+	if old, ok := s.items[id]; ok {
+		for name, indexer := range s.indexers {
+			key := indexer(old)
+			delete(s.indexes[name][key], id)
+			if len(s.indexes[name][key]) == 0 {
+				delete(s.indexes[name], key)
+			}
+		}
+		delete(s.items, id)
+	}
+	if deleted {
+		return
+	}
+	s.items[id] = item
+	for name, indexer := range s.indexers {
+		key := indexer(item)
+		if s.indexes[name][key] == nil {
+			s.indexes[name][key] = make(map[string]bool)
+		}
+		s.indexes[name][key][id] = true
+	}
+}
+
+func (s *indexedStore[T]) get(id string) (T, bool) {
+	item, ok := s.items[id]
+	return item, ok
+}
+
+func (s *indexedStore[T]) list() []T {
+	ids := make([]string, 0, len(s.items))
+	for id := range s.items {
+		ids = append(ids, id)
+	}
+	return s.itemsOf(ids)
+}
+
+func (s *indexedStore[T]) byIndex(name, key string) []T {
+	ids := make([]string, 0, len(s.indexes[name][key]))
+	for id := range s.indexes[name][key] {
+		ids = append(ids, id)
+	}
+	return s.itemsOf(ids)
+}
+
+func (s *indexedStore[T]) itemsOf(ids []string) []T {
+	sort.Strings(ids)
+	items := make([]T, len(ids))
+	for i, id := range ids {
+		items[i] = s.items[id]
+	}
+	return items
+}
@@ -0,0 +1,106 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInformer(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	env := api.addEnvironment(HostingEnvironment{Name: "prod"})
+	lake := api.addDatalake(Datalake{HostingEnvironmentID: env.ID, Name: "lake", Labels: map[string]string{"team": "data"}})
+	app := api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Name: "app"})
+	link := api.addLink(SourceAppDatalakeLink{SourceAppID: app.ID, DatalakeID: lake.ID, HostingEnvironmentID: env.ID})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var events []Event
+	informer := client.NewInformer(WatchOptions{Interval: 10 * time.Millisecond})
+	informer.AddEventHandler(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	assert.False(t, informer.HasSynced())
+	go informer.Run(ctx)
+	assert.NoError(t, informer.WaitForCacheSync(ctx))
+	assert.True(t, informer.HasSynced())
+
+	datalakes := informer.Datalakes().ByHostingEnvironment(env.ID)
+	if assert.Len(t, datalakes, 1) {
+		assert.Equal(t, lake.ID, datalakes[0].ID)
+	}
+	assert.Len(t, informer.SourceApps().ByHostingEnvironment(env.ID), 1)
+	assert.Len(t, informer.HostingEnvironments().List(), 1)
+	links := informer.Links().BySourceApp(app.ID)
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, link.ID, links[0].ID)
+	}
+	assert.Len(t, informer.Links().ByDatalake(lake.ID), 1)
+	assert.Len(t, informer.Links().ByHostingEnvironment(env.ID), 1)
+
+	// Reads are served from memory and return copies
+	requests := api.count("GET", "/datalakes")
+	cached, err := informer.Datalakes().Get(lake.ID)
+	assert.NoError(t, err)
+	cached.Labels["team"] = "changed"
+	cached, _ = informer.Datalakes().Get(lake.ID)
+	assert.Equal(t, "data", cached.Labels["team"])
+	assert.Equal(t, requests, api.count("GET", "/datalakes"))
+	_, err = informer.SourceApps().Get("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// Moving a datalake updates the index
+	other := api.addEnvironment(HostingEnvironment{Name: "staging"})
+	api.mu.Lock()
+	api.datalakes[lake.ID].HostingEnvironmentID = other.ID
+	api.datalakes[lake.ID].UpdatedAt = time.Now()
+	api.mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return len(informer.Datalakes().ByHostingEnvironment(other.ID)) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, informer.Datalakes().ByHostingEnvironment(env.ID))
+
+	api.mu.Lock()
+	delete(api.links, link.ID)
+	api.mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return len(informer.Links().BySourceApp(app.ID)) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []string{
+		"added hosting_environment " + env.ID,
+		"added datalake " + lake.ID,
+		"added source_app " + app.ID,
+		"added source_app_datalake_link " + link.ID,
+		"added hosting_environment " + other.ID,
+		"modified datalake " + lake.ID,
+		"deleted source_app_datalake_link " + link.ID,
+	}, eventStrings(events))
+	mu.Unlock()
+
+	// Handlers added after syncing are told about cached resources first
+	var late []Event
+	informer.AddEventHandler(func(e Event) { late = append(late, e) })
+	assert.Len(t, late, 4)
+}
+
+func TestInformerSyncFailure(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	api.server.Close()
+
+	informer := client.NewInformer(WatchOptions{})
+	go informer.Run(context.Background())
+	err := informer.WaitForCacheSync(context.Background())
+	assert.ErrorContains(t, err, "informer failed to sync")
+	assert.False(t, informer.HasSynced())
+}
@@ -0,0 +1,327 @@
+package traceforce
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// Label keys may have a DNS-style prefix, e.g. "traceforce.co/team".
+	labelKeyPattern   = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+)
+
+// ValidateLabels checks that label keys and values are well formed: at most
+// 63 alphanumeric characters, '-', '_' or '.', starting and ending with an
+// alphanumeric character. Keys may have a DNS-style prefix such as
+// "example.com/". Values may be empty.
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if err := validateLabelValue(value); err != nil {
+			return fmt.Errorf("label %q: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func validateLabelKey(key string) error {
+	if !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
+
+func validateLabelValue(value string) error {
+	if !labelValuePattern.MatchString(value) {
+		return fmt.Errorf("invalid label value %q", value)
+	}
+	return nil
+}
+
+type selectorOperator string
+
+const (
+	selectorEquals    selectorOperator = "="
+	selectorNotEquals selectorOperator = "!="
+	selectorIn        selectorOperator = "in"
+	selectorNotIn     selectorOperator = "notin"
+	selectorExists    selectorOperator = "exists"
+	selectorNotExists selectorOperator = "!"
+)
+
+type labelRequirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	switch r.operator {
+	case selectorEquals:
+		return ok && value == r.values[0]
+	case selectorNotEquals:
+		return !ok || value != r.values[0]
+	case selectorIn:
+		return ok && containsString(r.values, value)
+	case selectorNotIn:
+		return !ok || !containsString(r.values, value)
+	case selectorExists:
+		return ok
+	case selectorNotExists:
+		return !ok
+	}
+	return false
+}
+
+func (r labelRequirement) String() string {
+	switch r.operator {
+	case selectorEquals, selectorNotEquals:
+		return r.key + string(r.operator) + r.values[0]
+	case selectorIn, selectorNotIn:
+		return r.key + " " + string(r.operator) + " (" + strings.Join(r.values, ",") + ")"
+	case selectorNotExists:
+		return "!" + r.key
+	}
+	return r.key
+}
+
+// LabelSelector selects resources by their labels. The zero value selects
+// everything.
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+// ParseLabelSelector parses a comma-separated list of requirements, all of
+// which have to hold:
+//
+//	team=data, team==data   label equals a value
+//	env!=prod               label is missing or differs
+//	tier in (gold,silver)   label is one of the values
+//	tier notin (bronze)     label is missing or none of the values
+//	owner                   label exists
+//	!owner                  label does not exist
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var s LabelSelector
+	terms, err := splitSelector(selector)
+	if err != nil {
+		return LabelSelector{}, err
+	}
+	for _, term := range terms {
+		requirement, err := parseRequirement(term)
+		if err != nil {
+			return LabelSelector{}, fmt.Errorf("invalid label selector %q: %v", selector, err)
+		}
+		s.requirements = append(s.requirements, requirement)
+	}
+	return s, nil
+}
+
+// MustParseLabelSelector is like ParseLabelSelector but panics if the
+// selector is invalid.
+func MustParseLabelSelector(selector string) LabelSelector {
+	s, err := ParseLabelSelector(selector)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Matches reports whether labels satisfy every requirement of the selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, requirement := range s.requirements {
+		if !requirement.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether the selector selects everything.
+func (s LabelSelector) IsZero() bool {
+	return len(s.requirements) == 0
+}
+
+// String returns the canonical form of the selector, as sent to the API.
+func (s LabelSelector) String() string {
+	terms := make([]string, 0, len(s.requirements))
+	for _, requirement := range s.requirements {
+		terms = append(terms, requirement.String())
+	}
+	return strings.Join(terms, ",")
+}
+
+// splitSelector splits a selector at the commas outside of parentheses.
+func splitSelector(selector string) ([]string, error) {
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("invalid label selector %q: nested parentheses", selector)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("invalid label selector %q: unbalanced parentheses", selector)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid label selector %q: unbalanced parentheses", selector)
+	}
+	terms = append(terms, selector[start:])
+
+	// An empty selector selects everything, but empty terms are mistakes
+	if len(terms) == 1 && strings.TrimSpace(terms[0]) == "" {
+		return nil, nil
+	}
+	for i, term := range terms {
+		terms[i] = strings.TrimSpace(term)
+		if terms[i] == "" {
+			return nil, fmt.Errorf("invalid label selector %q: empty requirement", selector)
+		}
+	}
+	return terms, nil
+}
+
+func parseRequirement(term string) (labelRequirement, error) {
+	if key, ok := strings.CutPrefix(term, "!"); ok && !strings.HasPrefix(key, "=") {
+		key = strings.TrimSpace(key)
+		return labelRequirement{key: key, operator: selectorNotExists}, validateLabelKey(key)
+	}
+
+	for _, operator := range []string{"!=", "==", "="} {
+		if key, value, ok := strings.Cut(term, operator); ok {
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if err := validateLabelKey(key); err != nil {
+				return labelRequirement{}, err
+			}
+			if err := validateLabelValue(value); err != nil {
+				return labelRequirement{}, err
+			}
+			op := selectorEquals
+			if operator == "!=" {
+				op = selectorNotEquals
+			}
+			return labelRequirement{key: key, operator: op, values: []string{value}}, nil
+		}
+	}
+
+	fields := strings.Fields(term)
+	if len(fields) == 1 && !strings.ContainsAny(term, "()") {
+		return labelRequirement{key: fields[0], operator: selectorExists}, validateLabelKey(fields[0])
+	}
+	if len(fields) < 2 {
+		return labelRequirement{}, fmt.Errorf("cannot parse requirement %q", term)
+	}
+
+	key := fields[0]
+	if err := validateLabelKey(key); err != nil {
+		return labelRequirement{}, err
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(term, key))
+	var op selectorOperator
+	switch {
+	case strings.HasPrefix(rest, "notin"):
+		op, rest = selectorNotIn, strings.TrimPrefix(rest, "notin")
+	case strings.HasPrefix(rest, "in"):
+		op, rest = selectorIn, strings.TrimPrefix(rest, "in")
+	default:
+		return labelRequirement{}, fmt.Errorf("unknown operator in requirement %q", term)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+		return labelRequirement{}, fmt.Errorf("expected a parenthesized value list in requirement %q", term)
+	}
+	var values []string
+	for _, value := range strings.Split(rest[1:len(rest)-1], ",") {
+		value = strings.TrimSpace(value)
+		if err := validateLabelValue(value); err != nil {
+			return labelRequirement{}, err
+		}
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return labelRequirement{key: key, operator: op, values: values}, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// labeled is implemented by resources that carry labels.
+type labeled interface {
+	labelSet() map[string]string
+}
+
+// filterByLabels returns the items matching selector, keeping their order.
+func filterByLabels[T labeled](items []T, selector LabelSelector) []T {
+	if selector.IsZero() {
+		return items
+	}
+	matching := items[:0:0]
+	for _, item := range items {
+		if selector.Matches(item.labelSet()) {
+			matching = append(matching, item)
+		}
+	}
+	return matching
+}
+
+// validateLabelsAndAnnotations validates the labels of a request and the
+// keys of its annotations, whose values are free-form.
+func validateLabelsAndAnnotations(labels, annotations map[string]string) error {
+	if err := ValidateLabels(labels); err != nil {
+		return err
+	}
+	for key := range annotations {
+		if err := validateLabelKey(key); err != nil {
+			return fmt.Errorf("invalid annotation key %q", key)
+		}
+	}
+	return nil
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// mapsEqual reports whether two label or annotation maps are equal, treating
+// nil and empty maps alike.
+func mapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
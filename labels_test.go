@@ -0,0 +1,150 @@
+package traceforce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	labels := map[string]string{"team": "data", "env": "staging", "tier": "gold"}
+
+	tests := []struct {
+		selector  string
+		canonical string
+		matches   bool
+	}{
+		{"", "", true},
+		{"team=data", "team=data", true},
+		{"team==data", "team=data", true},
+		{"team=data,env!=prod", "team=data,env!=prod", true},
+		{"team = data , env != staging", "team=data,env!=staging", false},
+		{"tier in (silver, gold)", "tier in (gold,silver)", true},
+		{"tier notin (gold)", "tier notin (gold)", false},
+		{"missing notin (gold)", "missing notin (gold)", true},
+		{"team", "team", true},
+		{"!team", "!team", false},
+		{"!owner,team=data", "!owner,team=data", true},
+		{"traceforce.co/owner", "traceforce.co/owner", false},
+	}
+	for _, tt := range tests {
+		selector, err := ParseLabelSelector(tt.selector)
+		if !assert.NoError(t, err, tt.selector) {
+			continue
+		}
+		assert.Equal(t, tt.canonical, selector.String(), tt.selector)
+		assert.Equal(t, tt.matches, selector.Matches(labels), tt.selector)
+	}
+
+	for _, invalid := range []string{
+		"team=data,",
+		"team in (data",
+		"team in data",
+		"team ~ data",
+		"-team=data",
+		"team=da ta",
+		"tier in ((gold))",
+		"!",
+	} {
+		_, err := ParseLabelSelector(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	assert.Panics(t, func() { MustParseLabelSelector("team in (") })
+}
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, ValidateLabels(map[string]string{
+		"team":                 "data",
+		"traceforce.co/ticket": "OPS-123",
+		"cost_center":          "",
+		"a.b-c":                "x_y.z",
+	}))
+	assert.Error(t, ValidateLabels(map[string]string{"team!": "data"}))
+	assert.Error(t, ValidateLabels(map[string]string{"team": "-data"}))
+
+	client, err := NewClient("test-key", "https://example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	_, err = client.CreateSourceApp(context.Background(), CreateSourceAppRequest{
+		Name:   "app",
+		Labels: map[string]string{"bad key": "x"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid label key")
+
+	_, err = client.CreateSourceApp(context.Background(), CreateSourceAppRequest{
+		Name:        "app",
+		Annotations: map[string]string{"bad key": "x"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid annotation key")
+}
+
+func TestWithLabelSelector(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("label_selector")
+		// The API may not filter, so the client filters again
+		w.Write([]byte(`[
+			{"id": "1", "name": "a", "labels": {"team": "data", "env": "prod"}},
+			{"id": "2", "name": "b", "labels": {"team": "data", "env": "dev"}},
+			{"id": "3", "name": "c"}
+		]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	selector := MustParseLabelSelector("team=data,env!=prod")
+
+	datalakes, err := client.GetDatalakesByHostingEnvironment(ctx, "550e8400-e29b-41d4-a716-446655440000", WithLabelSelector(selector))
+	assert.NoError(t, err)
+	assert.Equal(t, "team=data,env!=prod", query)
+	if assert.Len(t, datalakes, 1) {
+		assert.Equal(t, "2", datalakes[0].ID)
+		assert.Equal(t, "dev", datalakes[0].Labels["env"])
+	}
+
+	links, err := client.GetSourceAppDatalakeLinks(ctx, WithLabelSelector(MustParseLabelSelector("!team")))
+	assert.NoError(t, err)
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, "3", links[0].ID)
+	}
+
+	environments, err := client.GetHostingEnvironments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "", query)
+	assert.Len(t, environments, 3)
+}
+
+func TestUpdateLabelsWithRetry(t *testing.T) {
+	backend := &versionedServer{datalake: Datalake{
+		ID:     concurrencyTestID,
+		Labels: map[string]string{"team": "data"},
+	}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	// Mutating the labels in place is detected as a change
+	_, err = client.UpdateDatalakeWithRetry(ctx, concurrencyTestID, func(d *Datalake) error {
+		d.Labels["owner"] = "ops"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, backend.patches)
+	assert.Equal(t, Some(map[string]string{"team": "data", "owner": "ops"}), backend.lastRequest.Labels)
+}
@@ -0,0 +1,166 @@
+package traceforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// secretFieldNames lists JSON fields that are always redacted from logged
+// bodies, in addition to any field whose name contains "secret", "password"
+// or "token".
+var secretFieldNames = map[string]bool{
+	"salesforce_client_secret": true,
+	"client_secret":            true,
+	"api_key":                  true,
+}
+
+type attemptContextKey struct{}
+
+// withAttempt records the attempt number of a request in its context.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptContextKey{}, attempt)
+}
+
+func attemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptContextKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// do sends req, logging a debug record for it and dumping the exchange when
+// a debug writer is configured.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	requestID := req.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = uuid.NewString()
+		req.Header.Set(requestIDHeader, requestID)
+	}
+
+	enabled := c.logger.Enabled(ctx, slog.LevelDebug)
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("endpoint", req.URL.Scheme+"://"+req.URL.Host),
+		slog.String("path", req.URL.Path),
+		slog.Int("attempt", attemptFromContext(ctx)),
+		slog.String("request_id", requestID),
+	}
+	if enabled && c.logBodies {
+		attrs = append(attrs, slog.Any("request_headers", redactHeaders(req.Header)))
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				data, _ := io.ReadAll(body)
+				attrs = append(attrs, slog.String("request_body", redactBody(data)))
+			}
+		}
+	}
+
+	if c.debug != nil {
+		c.debug.dumpRequest(req)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	attrs = append(attrs, slog.Duration("latency", time.Since(start)))
+	if err != nil {
+		if enabled {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			c.logger.LogAttrs(ctx, slog.LevelDebug, "traceforce request failed", attrs...)
+		}
+		return nil, err
+	}
+
+	if c.debug != nil {
+		c.debug.dumpResponse(resp)
+	}
+
+	if enabled {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		if id := resp.Header.Get(requestIDHeader); id != "" && id != requestID {
+			attrs = append(attrs, slog.String("server_request_id", id))
+		}
+		if c.logBodies {
+			data, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+			if readErr == nil {
+				attrs = append(attrs, slog.String("response_body", redactBody(data)))
+			}
+		}
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "traceforce request", attrs...)
+	}
+
+	return resp, nil
+}
+
+// redactHeaders returns a copy of header with credentials masked.
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for k := range header {
+		if strings.EqualFold(k, "Authorization") || isSecretField(k) {
+			redacted[k] = redactedSecret
+			continue
+		}
+		redacted[k] = header.Get(k)
+	}
+	return redacted
+}
+
+// redactBody returns a JSON body with secret fields masked. Bodies that are
+// not JSON are not logged at all since they cannot be redacted reliably.
+func redactBody(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Sprintf("<%d bytes of non-JSON body>", len(data))
+	}
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes of unprintable body>", len(data))
+	}
+	return string(redacted)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if isSecretField(k) {
+				if s, ok := field.(string); ok && s == "" {
+					continue
+				}
+				v[k] = redactedSecret
+				continue
+			}
+			v[k] = redactValue(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	return secretFieldNames[name] ||
+		strings.Contains(name, "secret") ||
+		strings.Contains(name, "password") ||
+		strings.Contains(name, "token")
+}
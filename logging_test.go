@@ -0,0 +1,73 @@
+package traceforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "lake", "api_key": "leaked"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := NewClient("test-api-key", server.URL, &ClientOptions{Logger: logger, LogBodies: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	_, err = client.GetDatalake(ctx, "550e8400-e29b-41d4-a716-446655440000")
+	assert.NoError(t, err)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "traceforce request", record["msg"])
+	assert.Equal(t, "GET", record["method"])
+	assert.Equal(t, "/datalakes/550e8400-e29b-41d4-a716-446655440000", record["path"])
+	assert.Equal(t, float64(200), record["status"])
+	assert.Equal(t, float64(1), record["attempt"])
+	assert.NotEmpty(t, record["request_id"])
+	assert.Contains(t, record, "latency")
+	assert.Contains(t, record["response_body"], `"name":"lake"`)
+
+	assert.NotContains(t, buf.String(), "test-api-key")
+	assert.NotContains(t, buf.String(), "leaked")
+}
+
+func TestRequestLoggingWithoutBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := NewClient("test-api-key", server.URL, &ClientOptions{Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	_, err = client.GetDatalakes(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"status":200`)
+	assert.NotContains(t, buf.String(), "response_body")
+}
+
+func TestRedactBody(t *testing.T) {
+	body := redactBody([]byte(`{"infrastructure": {"salesforce": {"salesforce_client_id": "id", "salesforce_client_secret": "s3cret"}}}`))
+	assert.NotContains(t, body, "s3cret")
+	assert.Contains(t, body, `"salesforce_client_id":"id"`)
+
+	assert.Equal(t, "<8 bytes of non-JSON body>", redactBody([]byte("not json")))
+}
@@ -0,0 +1,77 @@
+package traceforce
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+type optionalState uint8
+
+const (
+	optionalUnset optionalState = iota
+	optionalNull
+	optionalSet
+)
+
+// Optional is a field of an update request with three states: unset fields
+// are left unchanged, null fields are cleared and set fields are changed to
+// their value. The zero value is unset. Fields of this type must be tagged
+// omitzero so that unset fields are left out of the request.
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+// Some returns an Optional that changes a field to value.
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{value: value, state: optionalSet}
+}
+
+// Null returns an Optional that clears a field.
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+// IsZero reports whether the field is unset.
+func (o Optional[T]) IsZero() bool {
+	return o.state == optionalUnset
+}
+
+// IsNull reports whether the field is cleared.
+func (o Optional[T]) IsNull() bool {
+	return o.state == optionalNull
+}
+
+// Get returns the value of the field and whether it is set.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.state == optionalSet
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.state != optionalSet {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*o = Null[T]()
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*o = Some(value)
+	return nil
+}
+
+// optionalMap returns an Optional that changes a field to m, or clears it
+// when m is empty.
+func optionalMap[K comparable, V any](m map[K]V) Optional[map[K]V] {
+	if len(m) == 0 {
+		return Null[map[K]V]()
+	}
+	return Some(m)
+}
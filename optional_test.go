@@ -0,0 +1,77 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionalJSON(t *testing.T) {
+	name := "lake"
+	tests := []struct {
+		req  UpdateDatalakeRequest
+		want string
+	}{
+		{UpdateDatalakeRequest{}, `{}`},
+		{UpdateDatalakeRequest{Name: &name}, `{"name":"lake"}`},
+		{UpdateDatalakeRequest{Labels: Null[map[string]string]()}, `{"labels":null}`},
+		{UpdateDatalakeRequest{Labels: Some(map[string]string{"team": "data"})}, `{"labels":{"team":"data"}}`},
+		{UpdateDatalakeRequest{Settings: Some(map[string]interface{}{"retention_days": 30, "location": nil})}, `{"settings":{"location":null,"retention_days":30}}`},
+	}
+	for _, tt := range tests {
+		body, err := json.Marshal(tt.req)
+		assert.NoError(t, err)
+		assert.JSONEq(t, tt.want, string(body))
+
+		var decoded UpdateDatalakeRequest
+		assert.NoError(t, json.Unmarshal(body, &decoded))
+		assert.Equal(t, tt.req.Labels.IsZero(), decoded.Labels.IsZero())
+		assert.Equal(t, tt.req.Labels.IsNull(), decoded.Labels.IsNull())
+	}
+
+	labels, ok := Some(map[string]string{"a": "b"}).Get()
+	assert.True(t, ok)
+	assert.Equal(t, "b", labels["a"])
+	_, ok = Null[string]().Get()
+	assert.False(t, ok)
+}
+
+func TestUpdateClearsFields(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &body)
+		}
+		w.Write([]byte(`{
+			"id": "550e8400-e29b-41d4-a716-446655440000",
+			"name": "app",
+			"labels": {"team": "data"},
+			"settings": {"sync": {"interval": "1h", "objects": ["Account"]}}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.UpdateSourceAppWithRetry(context.Background(), "550e8400-e29b-41d4-a716-446655440000", func(s *SourceApp) error {
+		s.Labels = nil
+		s.Settings["sync"].(map[string]interface{})["interval"] = "15m"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"labels": nil,
+		"settings": map[string]interface{}{
+			"sync": map[string]interface{}{"interval": "15m", "objects": []interface{}{"Account"}},
+		},
+	}, body)
+}
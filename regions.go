@@ -0,0 +1,87 @@
+package traceforce
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Residency regions of the API.
+const (
+	RegionUS = "us"
+	RegionEU = "eu"
+)
+
+// regionBaseURLs maps residency regions to their API endpoints.
+var regionBaseURLs = map[string]string{
+	RegionUS: "https://api.traceforce.co/api/v1",
+	RegionEU: "https://api.eu.traceforce.co/api/v1",
+}
+
+// regionDatalakePrefixes lists, per residency region, the datalake regions
+// that keep data inside it. A region matches a prefix if it equals it or
+// starts with it followed by a dash, case-insensitively. This covers both
+// multi-regions such as BigQuery's "EU" and cloud regions such as
+// "europe-west1" or "eu-west-1". Canadian and Mexican regions such as
+// "northamerica-northeast1" are outside the US.
+var regionDatalakePrefixes = map[string][]string{
+	RegionUS: {"us"},
+	RegionEU: {"eu", "europe"},
+}
+
+// RegionBaseURL returns the API base URL of a residency region.
+func RegionBaseURL(region string) (string, error) {
+	url, ok := regionBaseURLs[strings.ToLower(region)]
+	if !ok {
+		return "", fmt.Errorf("invalid region %q, expected one of %s", region, strings.Join(regionNames(), ", "))
+	}
+	return url, nil
+}
+
+// regionOfBaseURL returns the residency region whose API endpoint url is,
+// or "" if it is none of them.
+func regionOfBaseURL(url string) string {
+	url = strings.TrimSuffix(strings.ToLower(url), "/")
+	for region, regionURL := range regionBaseURLs {
+		if url == regionURL {
+			return region
+		}
+	}
+	return ""
+}
+
+func regionNames() []string {
+	names := make([]string, 0, len(regionBaseURLs))
+	for name := range regionBaseURLs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// datalakeRegionInResidency reports whether a datalake region lies inside a
+// residency region.
+func datalakeRegionInResidency(datalakeRegion, residency string) bool {
+	datalakeRegion = strings.ToLower(datalakeRegion)
+	for _, prefix := range regionDatalakePrefixes[residency] {
+		if datalakeRegion == prefix || strings.HasPrefix(datalakeRegion, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDatalakeResidency warns, or fails when StrictResidency is set, if a
+// datalake region lies outside the residency region of the client.
+func (c *Client) checkDatalakeResidency(datalakeRegion string) error {
+	if c.region == "" || datalakeRegion == "" || datalakeRegionInResidency(datalakeRegion, c.region) {
+		return nil
+	}
+	if c.strictResidency {
+		return fmt.Errorf("datalake region %q is outside the %s residency region", datalakeRegion, c.region)
+	}
+	c.logger.Warn("datalake region is outside the residency region",
+		"datalake_region", datalakeRegion,
+		"residency_region", c.region)
+	return nil
+}
@@ -0,0 +1,103 @@
+package traceforce
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRegion(t *testing.T) {
+	client, err := NewClient("test-key", "", &ClientOptions{Region: "EU"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	assert.Equal(t, regionBaseURLs[RegionEU], client.baseURL)
+	assert.Equal(t, RegionEU, client.region)
+
+	// An explicit base URL wins, with a warning
+	var logs bytes.Buffer
+	client, err = NewClient("test-key", "https://proxy.example.com", &ClientOptions{
+		Region: RegionEU,
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	assert.Equal(t, "https://proxy.example.com", client.baseURL)
+	assert.Equal(t, RegionEU, client.region)
+	assert.Contains(t, logs.String(), "base URL overrides the endpoint of the residency region")
+
+	logs.Reset()
+	_, err = NewClient("test-key", regionBaseURLs[RegionEU], &ClientOptions{
+		Region: RegionEU,
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, logs.String())
+
+	// Strict residency refuses another region's endpoint
+	_, err = NewClient("test-key", regionBaseURLs[RegionUS], &ClientOptions{Region: RegionEU, StrictResidency: true})
+	assert.EqualError(t, err, "base URL https://api.traceforce.co/api/v1 is the endpoint of the us region, not of eu")
+	_, err = NewClient("test-key", "https://proxy.example.com", &ClientOptions{Region: RegionEU, StrictResidency: true})
+	assert.NoError(t, err)
+
+	_, err = NewClient("test-key", "", &ClientOptions{Region: "mars"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid region "mars", expected one of eu, us`)
+}
+
+func TestDatalakeRegionInResidency(t *testing.T) {
+	for _, region := range []string{"EU", "eu", "europe-west1", "europe-north1", "eu-west-1"} {
+		assert.True(t, datalakeRegionInResidency(region, RegionEU), region)
+		assert.False(t, datalakeRegionInResidency(region, RegionUS), region)
+	}
+	for _, region := range []string{"US", "us-central1", "us-east-1"} {
+		assert.True(t, datalakeRegionInResidency(region, RegionUS), region)
+		assert.False(t, datalakeRegionInResidency(region, RegionEU), region)
+	}
+	for _, region := range []string{"northamerica-northeast1", "northamerica-south1"} {
+		assert.False(t, datalakeRegionInResidency(region, RegionUS), region)
+	}
+	assert.False(t, datalakeRegionInResidency("asia-east1", RegionEU))
+	assert.False(t, datalakeRegionInResidency("europa", RegionEU))
+}
+
+func TestCreateDatalakeResidency(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000"}`))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	strict, err := NewClient("test-key", server.URL, &ClientOptions{Region: RegionEU, StrictResidency: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	_, err = strict.CreateDatalake(ctx, CreateDatalakeRequest{Name: "lake", Region: "us-central1"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "outside the eu residency region")
+	assert.Equal(t, 0, requests)
+
+	_, err = strict.CreateDatalake(ctx, CreateDatalakeRequest{Name: "lake", Region: "europe-west1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	var logs bytes.Buffer
+	lenient, err := NewClient("test-key", server.URL, &ClientOptions{
+		Region: RegionEU,
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	_, err = lenient.CreateDatalake(ctx, CreateDatalakeRequest{Name: "lake", Region: "US"})
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), "datalake region is outside the residency region")
+}
@@ -0,0 +1,143 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const redactedSecret = "[REDACTED]"
+
+// Secret holds a sensitive value such as a client secret. It redacts itself
+// when printed or marshalled to JSON, so it is safe to log resources that
+// contain one. The SDK reveals the value only when building request bodies.
+//
+// A Secret may instead hold a reference such as "env://SF_SECRET", created
+// with SecretRef. References are resolved by the client right before a
+// request is sent, see SecretResolver.
+type Secret struct {
+	value string
+	ref   string
+}
+
+// SecretProvider loads secrets by name from an external store.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, name string) (Secret, error)
+}
+
+// SecretProviderFunc adapts a function to the SecretProvider interface.
+type SecretProviderFunc func(ctx context.Context, name string) (Secret, error)
+
+func (f SecretProviderFunc) GetSecret(ctx context.Context, name string) (Secret, error) {
+	return f(ctx, name)
+}
+
+// NewSecret wraps a plain value in a Secret.
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// SecretRef creates a Secret that refers to a value held elsewhere, e.g.
+// "env://SF_SECRET", "file:///run/secrets/sf" or "exec://get-secret sf".
+func SecretRef(ref string) Secret {
+	return Secret{ref: ref}
+}
+
+// SecretFromEnv loads a secret from the named environment variable.
+func SecretFromEnv(name string) (Secret, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return Secret{}, fmt.Errorf("environment variable %s is not set", name)
+	}
+	return NewSecret(value), nil
+}
+
+// SecretFromFile loads a secret from a file, trimming any trailing newline.
+func SecretFromFile(path string) (Secret, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to read secret file: %v", err)
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return Secret{}, fmt.Errorf("secret file %s is empty", path)
+	}
+	return NewSecret(value), nil
+}
+
+// SecretFromProvider loads the named secret from provider.
+func SecretFromProvider(ctx context.Context, provider SecretProvider, name string) (Secret, error) {
+	if provider == nil {
+		return Secret{}, fmt.Errorf("secret provider cannot be nil")
+	}
+	secret, err := provider.GetSecret(ctx, name)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to load secret %s: %v", name, err)
+	}
+	if secret.IsZero() {
+		return Secret{}, fmt.Errorf("secret %s is empty", name)
+	}
+	return secret, nil
+}
+
+// Reveal returns the underlying value. It returns an empty string for an
+// unresolved reference.
+func (s Secret) Reveal() string {
+	return s.value
+}
+
+// IsReference reports whether the secret is an unresolved reference.
+func (s Secret) IsReference() bool {
+	return s.ref != ""
+}
+
+// IsZero reports whether the secret is empty.
+func (s Secret) IsZero() bool {
+	return s.value == "" && s.ref == ""
+}
+
+func (s Secret) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return redactedSecret
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("traceforce.Secret(%q)", s.String())
+}
+
+// Format implements fmt.Formatter so that every verb, including %+v and %#v
+// on structs embedding a Secret, prints the redacted form.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprint(f, s.GoString())
+		return
+	}
+	if verb != 'q' {
+		verb = 's'
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, verb), s.String())
+}
+
+// MarshalJSON always emits the redacted form. Request bodies are built from
+// revealed copies instead, see Infrastructure.wire.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON rejects the redacted form, so that a marshalled Secret read
+// back is not mistaken for the real value and sent to the API.
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == redactedSecret {
+		return fmt.Errorf("cannot unmarshal a redacted secret")
+	}
+	s.value = value
+	return nil
+}
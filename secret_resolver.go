@@ -0,0 +1,212 @@
+package traceforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SecretResolver resolves secret references of a single scheme. ref is the
+// full reference, including the scheme, e.g. "env://SF_SECRET".
+//
+// Resolvers are registered by scheme in ClientOptions.SecretResolvers. The
+// env, file and exec schemes are registered by default.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref string) (Secret, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface.
+type SecretResolverFunc func(ctx context.Context, ref string) (Secret, error)
+
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref string) (Secret, error) {
+	return f(ctx, ref)
+}
+
+// EnvSecretResolver resolves "env://NAME" references from the environment.
+type EnvSecretResolver struct{}
+
+func (EnvSecretResolver) ResolveSecret(ctx context.Context, ref string) (Secret, error) {
+	return SecretFromEnv(secretRefPath(ref))
+}
+
+// FileSecretResolver resolves "file:///path" references from the filesystem.
+type FileSecretResolver struct{}
+
+func (FileSecretResolver) ResolveSecret(ctx context.Context, ref string) (Secret, error) {
+	return SecretFromFile(secretRefPath(ref))
+}
+
+// ExecSecretResolver resolves "exec://command arg..." references by running
+// the command and reading the secret from its standard output.
+type ExecSecretResolver struct {
+	// Timeout bounds the command run time. Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+func (r ExecSecretResolver) ResolveSecret(ctx context.Context, ref string) (Secret, error) {
+	args := strings.Fields(secretRefPath(ref))
+	if len(args) == 0 {
+		return Secret{}, fmt.Errorf("exec secret reference has no command")
+	}
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		// stderr is deliberately not included, it may echo the secret.
+		return Secret{}, fmt.Errorf("secret command %s failed: %v", args[0], err)
+	}
+
+	value := strings.TrimRight(stdout.String(), "\r\n")
+	if value == "" {
+		return Secret{}, fmt.Errorf("secret command %s produced no output", args[0])
+	}
+	return NewSecret(value), nil
+}
+
+// HTTPSecretResolver resolves references against a Vault-style HTTP key/value
+// store. A reference "<scheme>://<path>#<key>" is fetched with
+// GET <BaseURL>/<path> and <key> is read from the JSON response. Both the
+// KV v1 shape {"data": {...}} and the KV v2 shape {"data": {"data": {...}}}
+// are understood. key defaults to "value".
+type HTTPSecretResolver struct {
+	BaseURL string
+	// Token is sent in TokenHeader with every request.
+	Token Secret
+	// TokenHeader defaults to "X-Vault-Token".
+	TokenHeader string
+	// HTTPClient defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+}
+
+func (r *HTTPSecretResolver) ResolveSecret(ctx context.Context, ref string) (Secret, error) {
+	path, key, _ := strings.Cut(secretRefPath(ref), "#")
+	if key == "" {
+		key = "value"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(r.BaseURL, "/")+"/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return Secret{}, err
+	}
+	if !r.Token.IsZero() {
+		header := r.TokenHeader
+		if header == "" {
+			header = "X-Vault-Token"
+		}
+		httpReq.Header.Set(header, r.Token.Reveal())
+	}
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return Secret{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		// The body is not included, a misconfigured store may echo secrets.
+		return Secret{}, fmt.Errorf("secret store returned HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Secret{}, fmt.Errorf("failed to decode secret store response: %v", err)
+	}
+
+	data := body.Data
+	if nested, ok := data["data"]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err == nil {
+			data = inner
+		}
+	}
+
+	var value string
+	if err := json.Unmarshal(data[key], &value); err != nil || value == "" {
+		return Secret{}, fmt.Errorf("secret store response has no string field %s", key)
+	}
+	return NewSecret(value), nil
+}
+
+func defaultSecretResolvers() map[string]SecretResolver {
+	return map[string]SecretResolver{
+		"env":  EnvSecretResolver{},
+		"file": FileSecretResolver{},
+		"exec": ExecSecretResolver{},
+	}
+}
+
+// secretRefPath strips the scheme from a reference.
+func secretRefPath(ref string) string {
+	_, path, _ := strings.Cut(ref, "://")
+	return path
+}
+
+// resolveSecret returns s unchanged unless it is a reference, in which case
+// the reference is resolved with the resolver registered for its scheme.
+// Resolved values are never included in errors.
+func (c *Client) resolveSecret(ctx context.Context, s Secret) (Secret, error) {
+	return resolveSecretWith(ctx, c.secretResolvers, s)
+}
+
+func resolveSecretWith(ctx context.Context, resolvers map[string]SecretResolver, s Secret) (Secret, error) {
+	if !s.IsReference() {
+		return s, nil
+	}
+
+	scheme, _, ok := strings.Cut(s.ref, "://")
+	if !ok {
+		return Secret{}, fmt.Errorf("invalid secret reference: missing scheme")
+	}
+	resolver, ok := resolvers[scheme]
+	if !ok {
+		return Secret{}, fmt.Errorf("no secret resolver registered for scheme %q", scheme)
+	}
+
+	resolved, err := resolver.ResolveSecret(ctx, s.ref)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to resolve %s secret reference: %v", scheme, err)
+	}
+	if resolved.IsReference() {
+		return Secret{}, fmt.Errorf("%s secret resolver returned another reference", scheme)
+	}
+	return resolved, nil
+}
+
+// resolveInfrastructureSecrets returns a copy of infra with every secret
+// reference resolved. infra itself is left untouched.
+func (c *Client) resolveInfrastructureSecrets(ctx context.Context, infra *Infrastructure) (*Infrastructure, error) {
+	if infra == nil {
+		return nil, nil
+	}
+
+	resolved := *infra
+	if infra.Salesforce != nil {
+		salesforce := *infra.Salesforce
+		secret, err := c.resolveSecret(ctx, salesforce.ClientSecret)
+		if err != nil {
+			return nil, fmt.Errorf("salesforce client secret: %v", err)
+		}
+		salesforce.ClientSecret = secret
+		resolved.Salesforce = &salesforce
+	}
+	return &resolved, nil
+}
@@ -0,0 +1,129 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretResolvers(t *testing.T) {
+	client, err := NewClient("test-key", "https://example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Setenv("SF_SECRET", "env-value")
+	secret, err := client.resolveSecret(ctx, SecretRef("env://SF_SECRET"))
+	assert.NoError(t, err)
+	assert.Equal(t, "env-value", secret.Reveal())
+
+	path := filepath.Join(t.TempDir(), "sf")
+	assert.NoError(t, os.WriteFile(path, []byte("file-value\n"), 0o600))
+	secret, err = client.resolveSecret(ctx, SecretRef("file://"+path))
+	assert.NoError(t, err)
+	assert.Equal(t, "file-value", secret.Reveal())
+
+	secret, err = client.resolveSecret(ctx, SecretRef("exec://echo exec-value"))
+	assert.NoError(t, err)
+	assert.Equal(t, "exec-value", secret.Reveal())
+
+	_, err = client.resolveSecret(ctx, SecretRef("vault://secret/sf"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no secret resolver registered")
+
+	plain, err := client.resolveSecret(ctx, NewSecret("plain"))
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", plain.Reveal())
+}
+
+func TestSecretRefIsRedacted(t *testing.T) {
+	ref := SecretRef("exec://get-secret --token abc")
+	assert.True(t, ref.IsReference())
+	assert.Equal(t, "", ref.Reveal())
+	assert.NotContains(t, fmt.Sprintf("%+v %#v", ref, ref), "abc")
+
+	jsonBody, err := json.Marshal(ref)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(jsonBody), "abc")
+}
+
+func TestHTTPSecretResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/salesforce":
+			w.Write([]byte(`{"data": {"data": {"client_secret": "kv2-value"}}}`))
+		case "/v1/secret/salesforce":
+			w.Write([]byte(`{"data": {"value": "kv1-value"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resolver := &HTTPSecretResolver{
+		BaseURL: server.URL + "/v1",
+		Token:   NewSecret("vault-token"),
+	}
+	ctx := context.Background()
+
+	secret, err := resolver.ResolveSecret(ctx, "vault://secret/data/salesforce#client_secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "kv2-value", secret.Reveal())
+
+	secret, err = resolver.ResolveSecret(ctx, "vault://secret/salesforce")
+	assert.NoError(t, err)
+	assert.Equal(t, "kv1-value", secret.Reveal())
+
+	_, err = resolver.ResolveSecret(ctx, "vault://secret/missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 404")
+}
+
+func TestPostConnectionResolvesSecretRefs(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, &ClientOptions{
+		SecretResolvers: map[string]SecretResolver{
+			"vault": SecretResolverFunc(func(ctx context.Context, ref string) (Secret, error) {
+				return NewSecret("vault-value"), nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	infrastructure := &Infrastructure{
+		Salesforce: &SalesforceInfrastructure{
+			ClientID:     "client-id",
+			ClientSecret: SecretRef("vault://secret/sf"),
+		},
+	}
+	err = client.PostConnection(context.Background(), "550e8400-e29b-41d4-a716-446655440000", &PostConnectionRequest{
+		Infrastructure:          infrastructure,
+		TerraformModuleVersions: "{}",
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"salesforce_client_secret":"vault-value"`)
+
+	// The caller's request is not modified
+	assert.True(t, infrastructure.Salesforce.ClientSecret.IsReference())
+}
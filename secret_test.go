@@ -0,0 +1,103 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	infra := &SalesforceInfrastructure{
+		ClientID:     "client-id",
+		Domain:       "example.my.salesforce.com",
+		ClientSecret: NewSecret("super-secret-value"),
+	}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q"} {
+		out := fmt.Sprintf(format, infra)
+		assert.NotContains(t, out, "super-secret-value", format)
+		assert.Contains(t, out, redactedSecret, format)
+	}
+
+	jsonBody, err := json.Marshal(infra)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(jsonBody), "super-secret-value")
+
+	assert.Equal(t, "super-secret-value", infra.ClientSecret.Reveal())
+	assert.Equal(t, "", NewSecret("").String())
+}
+
+func TestSecretUnmarshalJSON(t *testing.T) {
+	var infra SalesforceInfrastructure
+	err := json.Unmarshal([]byte(`{"salesforce_client_secret":"from-json"}`), &infra)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-json", infra.ClientSecret.Reveal())
+
+	// The redacted form does not round-trip into a real value
+	data, err := json.Marshal(SalesforceInfrastructure{ClientSecret: NewSecret("s3cr3t")})
+	assert.NoError(t, err)
+	var roundTripped SalesforceInfrastructure
+	err = json.Unmarshal(data, &roundTripped)
+	assert.ErrorContains(t, err, "cannot unmarshal a redacted secret")
+	assert.True(t, roundTripped.ClientSecret.IsZero())
+}
+
+func TestSecretLoaders(t *testing.T) {
+	t.Setenv("TRACEFORCE_TEST_SECRET", "env-value")
+	secret, err := SecretFromEnv("TRACEFORCE_TEST_SECRET")
+	assert.NoError(t, err)
+	assert.Equal(t, "env-value", secret.Reveal())
+
+	_, err = SecretFromEnv("TRACEFORCE_TEST_SECRET_MISSING")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(path, []byte("file-value\n"), 0o600))
+	secret, err = SecretFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "file-value", secret.Reveal())
+
+	provider := SecretProviderFunc(func(ctx context.Context, name string) (Secret, error) {
+		return NewSecret("provided-" + name), nil
+	})
+	secret, err = SecretFromProvider(context.Background(), provider, "sf")
+	assert.NoError(t, err)
+	assert.Equal(t, "provided-sf", secret.Reveal())
+}
+
+func TestPostConnectionRevealsSecretOnWire(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	req := &PostConnectionRequest{
+		Infrastructure: &Infrastructure{
+			Salesforce: &SalesforceInfrastructure{
+				ClientID:     "client-id",
+				Domain:       "example.my.salesforce.com",
+				ClientSecret: NewSecret("super-secret-value"),
+			},
+		},
+		TerraformModuleVersions: "{}",
+	}
+	err = client.PostConnection(ctx, "550e8400-e29b-41d4-a716-446655440000", req)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"salesforce_client_secret":"super-secret-value"`)
+}
@@ -1,33 +1,44 @@
 package traceforce
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Request types
 type CreateSourceAppDatalakeLinkRequest struct {
-	SourceAppID string `json:"source_app_id"`
-	DatalakeID  string `json:"datalake_id"`
+	SourceAppID string            `json:"source_app_id"`
+	DatalakeID  string            `json:"datalake_id"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // Response type
 type SourceAppDatalakeLink struct {
-	ID                   string    `json:"id"`
-	SourceAppID          string    `json:"source_app_id"`
-	DatalakeID           string    `json:"datalake_id"`
-	HostingEnvironmentID string    `json:"hosting_environment_id"`
-	CreatedAt            time.Time `json:"created_at"`
-	UpdatedAt            time.Time `json:"updated_at"`
+	ID                   string            `json:"id"`
+	SourceAppID          string            `json:"source_app_id"`
+	DatalakeID           string            `json:"datalake_id"`
+	HostingEnvironmentID string            `json:"hosting_environment_id"`
+	Labels               map[string]string `json:"labels,omitempty"`
+	Annotations          map[string]string `json:"annotations,omitempty"`
+	CreatedAt            time.Time         `json:"created_at"`
+	UpdatedAt            time.Time         `json:"updated_at"`
 }
 
-func (c *Client) CreateSourceAppDatalakeLink(req CreateSourceAppDatalakeLinkRequest) (*SourceAppDatalakeLink, error) {
+func (l SourceAppDatalakeLink) labelSet() map[string]string {
+	return l.Labels
+}
+
+func (l SourceAppDatalakeLink) resourceID() string {
+	return l.ID
+}
+
+func (c *Client) CreateSourceAppDatalakeLink(ctx context.Context, req CreateSourceAppDatalakeLinkRequest, opts ...CallOption) (*SourceAppDatalakeLink, error) {
 	if req.SourceAppID == "" {
 		return nil, fmt.Errorf("source app ID cannot be empty")
 	}
@@ -48,33 +59,22 @@ func (c *Client) CreateSourceAppDatalakeLink(req CreateSourceAppDatalakeLinkRequ
 		return nil, fmt.Errorf("invalid datalake ID UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps-datalakes"
-	headers := c.buildHeaders()
-
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
-	}
-
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { httpReq.Header.Set(k, v) }
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
+	if err := validateLabelsAndAnnotations(req.Labels, req.Annotations); err != nil {
 		return nil, err
 	}
 
 	var createdLink SourceAppDatalakeLink
-	err = json.NewDecoder(resp.Body).Decode(&createdLink)
+	err = c.call(ctx, apiRequest{
+		operation: "CreateSourceAppDatalakeLink",
+		method:    "POST",
+		path:      "/source-apps-datalakes",
+		body:      req,
+		attrs: []attribute.KeyValue{
+			attrSourceAppID.String(req.SourceAppID),
+			attrDatalakeID.String(req.DatalakeID),
+		},
+		opts: opts,
+	}, &createdLink)
 	if err != nil {
 		return nil, err
 	}
@@ -82,35 +82,22 @@ func (c *Client) CreateSourceAppDatalakeLink(req CreateSourceAppDatalakeLinkRequ
 	return &createdLink, nil
 }
 
-func (c *Client) GetSourceAppDatalakeLinks() ([]SourceAppDatalakeLink, error) {
-	url := c.baseURL + "/source-apps-datalakes"
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
+func (c *Client) GetSourceAppDatalakeLinks(ctx context.Context, opts ...CallOption) ([]SourceAppDatalakeLink, error) {
 	var links []SourceAppDatalakeLink
-	err = json.NewDecoder(resp.Body).Decode(&links)
+	err := c.call(ctx, apiRequest{
+		operation: "GetSourceAppDatalakeLinks",
+		method:    "GET",
+		path:      "/source-apps-datalakes",
+		opts:      opts,
+	}, &links)
 	if err != nil {
 		return nil, err
 	}
 
-	return links, nil
+	return filterByLabels(links, newCallOptions(opts).labelSelector), nil
 }
 
-func (c *Client) GetSourceAppDatalakeLinksBySourceApp(sourceAppID string) ([]SourceAppDatalakeLink, error) {
+func (c *Client) GetSourceAppDatalakeLinksBySourceApp(ctx context.Context, sourceAppID string, opts ...CallOption) ([]SourceAppDatalakeLink, error) {
 	if sourceAppID == "" {
 		return nil, fmt.Errorf("source app ID cannot be empty")
 	}
@@ -121,34 +108,22 @@ func (c *Client) GetSourceAppDatalakeLinksBySourceApp(sourceAppID string) ([]Sou
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps-datalakes?source_app_id=" + url.QueryEscape(sourceAppID)
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var links []SourceAppDatalakeLink
-	err = json.NewDecoder(resp.Body).Decode(&links)
+	err = c.call(ctx, apiRequest{
+		operation: "GetSourceAppDatalakeLinksBySourceApp",
+		method:    "GET",
+		path:      "/source-apps-datalakes?source_app_id=" + url.QueryEscape(sourceAppID),
+		attrs:     []attribute.KeyValue{attrSourceAppID.String(sourceAppID)},
+		opts:      opts,
+	}, &links)
 	if err != nil {
 		return nil, err
 	}
 
-	return links, nil
+	return filterByLabels(links, newCallOptions(opts).labelSelector), nil
 }
 
-func (c *Client) GetSourceAppDatalakeLinksByDatalake(datalakeID string) ([]SourceAppDatalakeLink, error) {
+func (c *Client) GetSourceAppDatalakeLinksByDatalake(ctx context.Context, datalakeID string, opts ...CallOption) ([]SourceAppDatalakeLink, error) {
 	if datalakeID == "" {
 		return nil, fmt.Errorf("datalake ID cannot be empty")
 	}
@@ -159,34 +134,22 @@ func (c *Client) GetSourceAppDatalakeLinksByDatalake(datalakeID string) ([]Sourc
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps-datalakes?datalake_id=" + url.QueryEscape(datalakeID)
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var links []SourceAppDatalakeLink
-	err = json.NewDecoder(resp.Body).Decode(&links)
+	err = c.call(ctx, apiRequest{
+		operation: "GetSourceAppDatalakeLinksByDatalake",
+		method:    "GET",
+		path:      "/source-apps-datalakes?datalake_id=" + url.QueryEscape(datalakeID),
+		attrs:     []attribute.KeyValue{attrDatalakeID.String(datalakeID)},
+		opts:      opts,
+	}, &links)
 	if err != nil {
 		return nil, err
 	}
 
-	return links, nil
+	return filterByLabels(links, newCallOptions(opts).labelSelector), nil
 }
 
-func (c *Client) GetSourceAppDatalakeLink(id string) (*SourceAppDatalakeLink, error) {
+func (c *Client) GetSourceAppDatalakeLink(ctx context.Context, id string, opts ...CallOption) (*SourceAppDatalakeLink, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id cannot be empty")
 	}
@@ -197,26 +160,14 @@ func (c *Client) GetSourceAppDatalakeLink(id string) (*SourceAppDatalakeLink, er
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps-datalakes/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var link SourceAppDatalakeLink
-	err = json.NewDecoder(resp.Body).Decode(&link)
+	err = c.call(ctx, apiRequest{
+		operation: "GetSourceAppDatalakeLink",
+		method:    "GET",
+		path:      "/source-apps-datalakes/" + id,
+		attrs:     []attribute.KeyValue{attrLinkID.String(id)},
+		opts:      opts,
+	}, &link)
 	if err != nil {
 		return nil, err
 	}
@@ -224,7 +175,7 @@ func (c *Client) GetSourceAppDatalakeLink(id string) (*SourceAppDatalakeLink, er
 	return &link, nil
 }
 
-func (c *Client) DeleteSourceAppDatalakeLink(id string) error {
+func (c *Client) DeleteSourceAppDatalakeLink(ctx context.Context, id string, opts ...CallOption) error {
 	if id == "" {
 		return fmt.Errorf("id cannot be empty")
 	}
@@ -235,23 +186,11 @@ func (c *Client) DeleteSourceAppDatalakeLink(id string) error {
 		return fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps-datalakes/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return err
-	}
-
-	return nil
-}
\ No newline at end of file
+	return c.call(ctx, apiRequest{
+		operation: "DeleteSourceAppDatalakeLink",
+		method:    "DELETE",
+		path:      "/source-apps-datalakes/" + id,
+		attrs:     []attribute.KeyValue{attrLinkID.String(id)},
+		opts:      opts,
+	}, nil)
+}
@@ -1,6 +1,7 @@
 package traceforce
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -12,6 +13,7 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	// First create a hosting environment
 	environmentReq := CreateHostingEnvironmentRequest{
@@ -21,12 +23,12 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 		NativeID:      "123456789012",
 	}
 
-	createdEnvironment, err := client.CreateHostingEnvironment(environmentReq)
+	createdEnvironment, err := client.CreateHostingEnvironment(ctx, environmentReq)
 	if err != nil {
 		t.Fatalf("Failed to create hosting environment: %v", err)
 	}
 	defer func() {
-		err := client.DeleteHostingEnvironment(createdEnvironment.ID)
+		err := client.DeleteHostingEnvironment(ctx, createdEnvironment.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup hosting environment: %v", err)
 		}
@@ -41,12 +43,12 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 		Region:               "us-central1",
 	}
 
-	createdDatalake, err := client.CreateDatalake(datalakeReq)
+	createdDatalake, err := client.CreateDatalake(ctx, datalakeReq)
 	if err != nil {
 		t.Fatalf("Failed to create datalake: %v", err)
 	}
 	defer func() {
-		err := client.DeleteDatalake(createdDatalake.ID)
+		err := client.DeleteDatalake(ctx, createdDatalake.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup datalake: %v", err)
 		}
@@ -59,12 +61,12 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 		Name:                 "test source app for links",
 	}
 
-	createdSourceApp, err := client.CreateSourceApp(sourceAppReq)
+	createdSourceApp, err := client.CreateSourceApp(ctx, sourceAppReq)
 	if err != nil {
 		t.Fatalf("Failed to create source app: %v", err)
 	}
 	defer func() {
-		err := client.DeleteSourceApp(createdSourceApp.ID)
+		err := client.DeleteSourceApp(ctx, createdSourceApp.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup source app: %v", err)
 		}
@@ -76,7 +78,7 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 		DatalakeID:  createdDatalake.ID,
 	}
 
-	createdLink, err := client.CreateSourceAppDatalakeLink(linkReq)
+	createdLink, err := client.CreateSourceAppDatalakeLink(ctx, linkReq)
 	if err != nil {
 		t.Fatalf("Failed to create source app datalake link: %v", err)
 	}
@@ -89,7 +91,7 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 	assert.NotEmpty(t, createdLink.ID)
 
 	// Test GetSourceAppDatalakeLinks
-	links, err := client.GetSourceAppDatalakeLinks()
+	links, err := client.GetSourceAppDatalakeLinks(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get source app datalake links: %v", err)
 	}
@@ -115,7 +117,7 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 	assert.NotNil(t, testLink)
 
 	// Test GetSourceAppDatalakeLink by ID
-	linkByID, err := client.GetSourceAppDatalakeLink(testLink.ID)
+	linkByID, err := client.GetSourceAppDatalakeLink(ctx, testLink.ID)
 	if err != nil {
 		t.Fatalf("Failed to get source app datalake link by ID: %v", err)
 	}
@@ -126,7 +128,7 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 	assert.Equal(t, testLink.DatalakeID, linkByID.DatalakeID)
 
 	// Test GetSourceAppDatalakeLinksBySourceApp
-	linksBySourceApp, err := client.GetSourceAppDatalakeLinksBySourceApp(createdSourceApp.ID)
+	linksBySourceApp, err := client.GetSourceAppDatalakeLinksBySourceApp(ctx, createdSourceApp.ID)
 	if err != nil {
 		t.Fatalf("Failed to get source app datalake links by source app: %v", err)
 	}
@@ -144,7 +146,7 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 	assert.True(t, found, "Test link should be found in source app links")
 
 	// Test GetSourceAppDatalakeLinksByDatalake
-	linksByDatalake, err := client.GetSourceAppDatalakeLinksByDatalake(createdDatalake.ID)
+	linksByDatalake, err := client.GetSourceAppDatalakeLinksByDatalake(ctx, createdDatalake.ID)
 	if err != nil {
 		t.Fatalf("Failed to get source app datalake links by datalake: %v", err)
 	}
@@ -162,7 +164,7 @@ func TestSourceAppDatalakeLinks(t *testing.T) {
 	assert.True(t, found, "Test link should be found in datalake links")
 
 	// Test DeleteSourceAppDatalakeLink
-	err = client.DeleteSourceAppDatalakeLink(testLink.ID)
+	err = client.DeleteSourceAppDatalakeLink(ctx, testLink.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete source app datalake link: %v", err)
 	}
@@ -175,9 +177,10 @@ func TestSourceAppDatalakeLinkValidation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	// Test CreateSourceAppDatalakeLink with empty source app ID
-	_, err = client.CreateSourceAppDatalakeLink(CreateSourceAppDatalakeLinkRequest{
+	_, err = client.CreateSourceAppDatalakeLink(ctx, CreateSourceAppDatalakeLinkRequest{
 		SourceAppID: "",
 		DatalakeID:  "123e4567-e89b-12d3-a456-426614174000",
 	})
@@ -185,7 +188,7 @@ func TestSourceAppDatalakeLinkValidation(t *testing.T) {
 	assert.Contains(t, err.Error(), "source app ID cannot be empty")
 
 	// Test CreateSourceAppDatalakeLink with empty datalake ID
-	_, err = client.CreateSourceAppDatalakeLink(CreateSourceAppDatalakeLinkRequest{
+	_, err = client.CreateSourceAppDatalakeLink(ctx, CreateSourceAppDatalakeLinkRequest{
 		SourceAppID: "123e4567-e89b-12d3-a456-426614174000",
 		DatalakeID:  "",
 	})
@@ -193,7 +196,7 @@ func TestSourceAppDatalakeLinkValidation(t *testing.T) {
 	assert.Contains(t, err.Error(), "datalake ID cannot be empty")
 
 	// Test CreateSourceAppDatalakeLink with invalid source app ID UUID
-	_, err = client.CreateSourceAppDatalakeLink(CreateSourceAppDatalakeLinkRequest{
+	_, err = client.CreateSourceAppDatalakeLink(ctx, CreateSourceAppDatalakeLinkRequest{
 		SourceAppID: "invalid-uuid",
 		DatalakeID:  "123e4567-e89b-12d3-a456-426614174000",
 	})
@@ -201,7 +204,7 @@ func TestSourceAppDatalakeLinkValidation(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid source app ID UUID format")
 
 	// Test CreateSourceAppDatalakeLink with invalid datalake ID UUID
-	_, err = client.CreateSourceAppDatalakeLink(CreateSourceAppDatalakeLinkRequest{
+	_, err = client.CreateSourceAppDatalakeLink(ctx, CreateSourceAppDatalakeLinkRequest{
 		SourceAppID: "123e4567-e89b-12d3-a456-426614174000",
 		DatalakeID:  "invalid-uuid",
 	})
@@ -209,42 +212,42 @@ func TestSourceAppDatalakeLinkValidation(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid datalake ID UUID format")
 
 	// Test GetSourceAppDatalakeLinksBySourceApp with empty ID
-	_, err = client.GetSourceAppDatalakeLinksBySourceApp("")
+	_, err = client.GetSourceAppDatalakeLinksBySourceApp(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "source app ID cannot be empty")
 
 	// Test GetSourceAppDatalakeLinksBySourceApp with invalid UUID
-	_, err = client.GetSourceAppDatalakeLinksBySourceApp("invalid-uuid")
+	_, err = client.GetSourceAppDatalakeLinksBySourceApp(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test GetSourceAppDatalakeLinksByDatalake with empty ID
-	_, err = client.GetSourceAppDatalakeLinksByDatalake("")
+	_, err = client.GetSourceAppDatalakeLinksByDatalake(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "datalake ID cannot be empty")
 
 	// Test GetSourceAppDatalakeLinksByDatalake with invalid UUID
-	_, err = client.GetSourceAppDatalakeLinksByDatalake("invalid-uuid")
+	_, err = client.GetSourceAppDatalakeLinksByDatalake(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test GetSourceAppDatalakeLink with empty ID
-	_, err = client.GetSourceAppDatalakeLink("")
+	_, err = client.GetSourceAppDatalakeLink(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test GetSourceAppDatalakeLink with invalid UUID
-	_, err = client.GetSourceAppDatalakeLink("invalid-uuid")
+	_, err = client.GetSourceAppDatalakeLink(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test DeleteSourceAppDatalakeLink with empty ID
-	err = client.DeleteSourceAppDatalakeLink("")
+	err = client.DeleteSourceAppDatalakeLink(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test DeleteSourceAppDatalakeLink with invalid UUID
-	err = client.DeleteSourceAppDatalakeLink("invalid-uuid")
+	err = client.DeleteSourceAppDatalakeLink(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 }
\ No newline at end of file
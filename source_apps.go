@@ -1,14 +1,14 @@
 package traceforce
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"net/url"
+	"reflect"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type SourceAppStatus string
@@ -28,54 +28,75 @@ const (
 
 // Request types
 type CreateSourceAppRequest struct {
-	HostingEnvironmentID string        `json:"hosting_environment_id"`
-	Type                 SourceAppType `json:"type"`
-	Name                 string        `json:"name"`
+	HostingEnvironmentID string                 `json:"hosting_environment_id"`
+	Type                 SourceAppType          `json:"type"`
+	Name                 string                 `json:"name"`
+	Labels               map[string]string      `json:"labels,omitempty"`
+	Annotations          map[string]string      `json:"annotations,omitempty"`
+	Settings             map[string]interface{} `json:"settings,omitempty"`
 }
 
+// UpdateSourceAppRequest changes the fields that are set and leaves the others
+// unchanged. Optional fields are cleared when set to Null.
 type UpdateSourceAppRequest struct {
-	Name *string `json:"name,omitempty"`
+	Name        *string                          `json:"name,omitempty"`
+	Labels      Optional[map[string]string]      `json:"labels,omitzero"`
+	Annotations Optional[map[string]string]      `json:"annotations,omitzero"`
+	Settings    Optional[map[string]interface{}] `json:"settings,omitzero"`
 }
 
 // Response type
 type SourceApp struct {
-	ID                   string          `json:"id"`
-	HostingEnvironmentID string          `json:"hosting_environment_id"`
-	Type                 SourceAppType   `json:"type"`
-	Name                 string          `json:"name"`
-	Status               SourceAppStatus `json:"status"`
-	CreatedAt            time.Time       `json:"created_at"`
-	UpdatedAt            time.Time       `json:"updated_at"`
+	ID                   string                 `json:"id"`
+	HostingEnvironmentID string                 `json:"hosting_environment_id"`
+	Type                 SourceAppType          `json:"type"`
+	Name                 string                 `json:"name"`
+	Status               SourceAppStatus        `json:"status"`
+	Labels               map[string]string      `json:"labels,omitempty"`
+	Annotations          map[string]string      `json:"annotations,omitempty"`
+	Settings             map[string]interface{} `json:"settings,omitempty"`
+	CreatedAt            time.Time              `json:"created_at"`
+	UpdatedAt            time.Time              `json:"updated_at"`
+
+	// ETag identifies the version of the resource for WithIfMatch. It is
+	// only set on resources returned individually.
+	ETag string `json:"-"`
 }
 
-func (c *Client) CreateSourceApp(req CreateSourceAppRequest) (*SourceApp, error) {
-	url := c.baseURL + "/source-apps"
-	headers := c.buildHeaders()
+func (s *SourceApp) setETag(etag string) {
+	s.ETag = etag
+}
 
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
-	}
+func (s SourceApp) labelSet() map[string]string {
+	return s.Labels
+}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { httpReq.Header.Set(k, v) }
-	httpReq.Header.Set("Content-Type", "application/json")
+func (s SourceApp) resourceID() string {
+	return s.ID
+}
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (s *SourceApp) clone() *SourceApp {
+	c := *s
+	c.Labels = cloneMap(s.Labels)
+	c.Annotations = cloneMap(s.Annotations)
+	c.Settings = cloneSettings(s.Settings)
+	return &c
+}
 
-	if err := validateResponse(resp); err != nil {
+func (c *Client) CreateSourceApp(ctx context.Context, req CreateSourceAppRequest, opts ...CallOption) (*SourceApp, error) {
+	if err := validateLabelsAndAnnotations(req.Labels, req.Annotations); err != nil {
 		return nil, err
 	}
 
 	var createdSourceApp SourceApp
-	err = json.NewDecoder(resp.Body).Decode(&createdSourceApp)
+	err := c.call(ctx, apiRequest{
+		operation: "CreateSourceApp",
+		method:    "POST",
+		path:      "/source-apps",
+		body:      req,
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(req.HostingEnvironmentID)},
+		opts:      opts,
+	}, &createdSourceApp)
 	if err != nil {
 		return nil, err
 	}
@@ -83,36 +104,22 @@ func (c *Client) CreateSourceApp(req CreateSourceAppRequest) (*SourceApp, error)
 	return &createdSourceApp, nil
 }
 
-func (c *Client) GetSourceApps() ([]SourceApp, error) {
-	url := c.baseURL + "/source-apps"
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
+func (c *Client) GetSourceApps(ctx context.Context, opts ...CallOption) ([]SourceApp, error) {
 	var sourceApps []SourceApp
-	err = json.NewDecoder(resp.Body).Decode(&sourceApps)
+	err := c.call(ctx, apiRequest{
+		operation: "GetSourceApps",
+		method:    "GET",
+		path:      "/source-apps",
+		opts:      opts,
+	}, &sourceApps)
 	if err != nil {
 		return nil, err
 	}
 
-	return sourceApps, nil
+	return filterByLabels(sourceApps, newCallOptions(opts).labelSelector), nil
 }
 
-
-func (c *Client) GetSourceAppsByHostingEnvironment(hostingEnvironmentID string) ([]SourceApp, error) {
+func (c *Client) GetSourceAppsByHostingEnvironment(ctx context.Context, hostingEnvironmentID string, opts ...CallOption) ([]SourceApp, error) {
 	if hostingEnvironmentID == "" {
 		return nil, fmt.Errorf("hosting environment ID cannot be empty")
 	}
@@ -123,34 +130,22 @@ func (c *Client) GetSourceAppsByHostingEnvironment(hostingEnvironmentID string)
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps?hosting_environment_id=" + url.QueryEscape(hostingEnvironmentID)
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var sourceApps []SourceApp
-	err = json.NewDecoder(resp.Body).Decode(&sourceApps)
+	err = c.call(ctx, apiRequest{
+		operation: "GetSourceAppsByHostingEnvironment",
+		method:    "GET",
+		path:      "/source-apps?hosting_environment_id=" + url.QueryEscape(hostingEnvironmentID),
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(hostingEnvironmentID)},
+		opts:      opts,
+	}, &sourceApps)
 	if err != nil {
 		return nil, err
 	}
 
-	return sourceApps, nil
+	return filterByLabels(sourceApps, newCallOptions(opts).labelSelector), nil
 }
 
-func (c *Client) GetSourceApp(id string) (*SourceApp, error) {
+func (c *Client) GetSourceApp(ctx context.Context, id string, opts ...CallOption) (*SourceApp, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id cannot be empty")
 	}
@@ -161,26 +156,14 @@ func (c *Client) GetSourceApp(id string) (*SourceApp, error) {
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
-
 	var sourceApp SourceApp
-	err = json.NewDecoder(resp.Body).Decode(&sourceApp)
+	err = c.call(ctx, apiRequest{
+		operation: "GetSourceApp",
+		method:    "GET",
+		path:      "/source-apps/" + id,
+		attrs:     []attribute.KeyValue{attrSourceAppID.String(id)},
+		opts:      opts,
+	}, &sourceApp)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +171,17 @@ func (c *Client) GetSourceApp(id string) (*SourceApp, error) {
 	return &sourceApp, nil
 }
 
-func (c *Client) UpdateSourceApp(id string, req UpdateSourceAppRequest) (*SourceApp, error) {
+// GetSourceAppsByIDs fetches the source apps with the given IDs concurrently,
+// with at most ClientOptions.BatchConcurrency requests in flight. IDs that
+// could not be fetched are reported in the result's Errors instead of failing
+// the batch.
+func (c *Client) GetSourceAppsByIDs(ctx context.Context, ids []string, opts ...CallOption) *BatchResult[SourceApp] {
+	return fetchBatch(ctx, c, ids, func(ctx context.Context, id string) (*SourceApp, error) {
+		return c.GetSourceApp(ctx, id, opts...)
+	})
+}
+
+func (c *Client) UpdateSourceApp(ctx context.Context, id string, req UpdateSourceAppRequest, opts ...CallOption) (*SourceApp, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id cannot be empty")
 	}
@@ -199,41 +192,84 @@ func (c *Client) UpdateSourceApp(id string, req UpdateSourceAppRequest) (*Source
 		return nil, fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps/" + id
-	headers := c.buildHeaders()
-
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
+	labels, _ := req.Labels.Get()
+	annotations, _ := req.Annotations.Get()
+	if err := validateLabelsAndAnnotations(labels, annotations); err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonBody))
+	var updatedSourceApp SourceApp
+	err = c.call(ctx, apiRequest{
+		operation: "UpdateSourceApp",
+		method:    "PATCH",
+		path:      "/source-apps/" + id,
+		body:      req,
+		attrs:     []attribute.KeyValue{attrSourceAppID.String(id)},
+		opts:      opts,
+	}, &updatedSourceApp)
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range headers { httpReq.Header.Set(k, v) }
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return &updatedSourceApp, nil
+}
 
-	if err := validateResponse(resp); err != nil {
-		return nil, err
-	}
+// UpdateSourceAppWithRetry reads the source app, applies mutate to it and saves the
+// changes, conditional on the source app not having changed in between. If it
+// has, the cycle starts over with the new version, up to a few times, and an
+// idempotency key set with WithIdempotencyKey gets an attempt suffix. Only
+// fields accepted by UpdateSourceApp may be changed.
+func (c *Client) UpdateSourceAppWithRetry(ctx context.Context, id string, mutate func(*SourceApp) error, opts ...CallOption) (*SourceApp, error) {
+	return updateWithRetry(ctx,
+		func(ctx context.Context) (*SourceApp, error) {
+			return c.GetSourceApp(ctx, id, appendOptions(opts, noCache())...)
+		},
+		mutate,
+		func(ctx context.Context, attempt int, current, updated *SourceApp) (*SourceApp, error) {
+			req, changed, err := sourceAppUpdate(current, updated)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				return current, nil
+			}
+			return c.UpdateSourceApp(ctx, id, req, appendOptions(attemptOptions(opts, attempt), preconditionFor(current.ETag, current.UpdatedAt))...)
+		})
+}
 
-	var updatedSourceApp SourceApp
-	err = json.NewDecoder(resp.Body).Decode(&updatedSourceApp)
-	if err != nil {
-		return nil, err
+// sourceAppUpdate returns the request that turns current into updated.
+func sourceAppUpdate(current, updated *SourceApp) (UpdateSourceAppRequest, bool, error) {
+	var req UpdateSourceAppRequest
+	changed := false
+	if updated.Name != current.Name {
+		req.Name = &updated.Name
+		changed = true
+	}
+	if !mapsEqual(updated.Labels, current.Labels) {
+		req.Labels = optionalMap(updated.Labels)
+		changed = true
+	}
+	if !mapsEqual(updated.Annotations, current.Annotations) {
+		req.Annotations = optionalMap(updated.Annotations)
+		changed = true
+	}
+	if !settingsEqual(updated.Settings, current.Settings) {
+		req.Settings = optionalMap(updated.Settings)
+		changed = true
 	}
 
-	return &updatedSourceApp, nil
+	rest := *updated
+	rest.Name = current.Name
+	rest.Labels = current.Labels
+	rest.Annotations = current.Annotations
+	rest.Settings = current.Settings
+	if !reflect.DeepEqual(&rest, current) {
+		return req, false, fmt.Errorf("only the name, labels, annotations and settings of a source app can be updated")
+	}
+	return req, changed, nil
 }
 
-func (c *Client) DeleteSourceApp(id string) error {
+func (c *Client) DeleteSourceApp(ctx context.Context, id string, opts ...CallOption) error {
 	if id == "" {
 		return fmt.Errorf("id cannot be empty")
 	}
@@ -244,23 +280,11 @@ func (c *Client) DeleteSourceApp(id string) error {
 		return fmt.Errorf("invalid UUID format: %v", err)
 	}
 
-	url := c.baseURL + "/source-apps/" + id
-	headers := c.buildHeaders()
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-	for k, v := range headers { req.Header.Set(k, v) }
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if err := validateResponse(resp); err != nil {
-		return err
-	}
-
-	return nil
-}
\ No newline at end of file
+	return c.call(ctx, apiRequest{
+		operation: "DeleteSourceApp",
+		method:    "DELETE",
+		path:      "/source-apps/" + id,
+		attrs:     []attribute.KeyValue{attrSourceAppID.String(id)},
+		opts:      opts,
+	}, nil)
+}
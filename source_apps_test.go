@@ -1,6 +1,7 @@
 package traceforce
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -12,6 +13,7 @@ func TestSourceApps(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 	// First create a hosting environment for the datalake
 	environmentReq := CreateHostingEnvironmentRequest{
@@ -21,12 +23,12 @@ func TestSourceApps(t *testing.T) {
 		NativeID:      "123456789012",
 	}
 
-	createdEnvironment, err := client.CreateHostingEnvironment(environmentReq)
+	createdEnvironment, err := client.CreateHostingEnvironment(ctx, environmentReq)
 	if err != nil {
 		t.Fatalf("Failed to create hosting environment: %v", err)
 	}
 	defer func() {
-		err := client.DeleteHostingEnvironment(createdEnvironment.ID)
+		err := client.DeleteHostingEnvironment(ctx, createdEnvironment.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup hosting environment: %v", err)
 		}
@@ -39,12 +41,12 @@ func TestSourceApps(t *testing.T) {
 		Name:                 "test datalake for source app",
 	}
 
-	createdDatalake, err := client.CreateDatalake(datalakeReq)
+	createdDatalake, err := client.CreateDatalake(ctx, datalakeReq)
 	if err != nil {
 		t.Fatalf("Failed to create datalake: %v", err)
 	}
 	defer func() {
-		err := client.DeleteDatalake(createdDatalake.ID)
+		err := client.DeleteDatalake(ctx, createdDatalake.ID)
 		if err != nil {
 			t.Logf("Failed to cleanup datalake: %v", err)
 		}
@@ -57,7 +59,7 @@ func TestSourceApps(t *testing.T) {
 		Name:                 testSourceAppName,
 	}
 
-	createdSourceApp, err := client.CreateSourceApp(sourceAppReq)
+	createdSourceApp, err := client.CreateSourceApp(ctx, sourceAppReq)
 	if err != nil {
 		t.Fatalf("Failed to create source app: %v", err)
 	}
@@ -69,7 +71,7 @@ func TestSourceApps(t *testing.T) {
 	assert.Equal(t, sourceAppReq.HostingEnvironmentID, createdSourceApp.HostingEnvironmentID)
 	assert.Equal(t, SourceAppStatusPending, createdSourceApp.Status)
 
-	sourceApps, err := client.GetSourceApps()
+	sourceApps, err := client.GetSourceApps(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get source apps: %v", err)
 	}
@@ -95,7 +97,7 @@ func TestSourceApps(t *testing.T) {
 	t.Logf("Test source app: %+v", testSourceApp)
 	assert.NotNil(t, testSourceApp)
 
-	sourceAppByID, err := client.GetSourceApp(testSourceApp.ID)
+	sourceAppByID, err := client.GetSourceApp(ctx, testSourceApp.ID)
 	if err != nil {
 		t.Fatalf("Failed to get source app by ID: %v", err)
 	}
@@ -104,7 +106,7 @@ func TestSourceApps(t *testing.T) {
 	assert.Equal(t, testSourceApp.ID, sourceAppByID.ID)
 	assert.Equal(t, testSourceApp.Name, sourceAppByID.Name)
 
-	sourceAppsByEnvironment, err := client.GetSourceAppsByHostingEnvironment(createdEnvironment.ID)
+	sourceAppsByEnvironment, err := client.GetSourceAppsByHostingEnvironment(ctx, createdEnvironment.ID)
 	if err != nil {
 		t.Fatalf("Failed to get source apps by hosting environment: %v", err)
 	}
@@ -125,7 +127,7 @@ func TestSourceApps(t *testing.T) {
 	updateReq := UpdateSourceAppRequest{
 		Name: &newName,
 	}
-	updatedSourceApp, err := client.UpdateSourceApp(testSourceApp.ID, updateReq)
+	updatedSourceApp, err := client.UpdateSourceApp(ctx, testSourceApp.ID, updateReq)
 	if err != nil {
 		t.Fatalf("Failed to update source app: %v", err)
 	}
@@ -135,7 +137,7 @@ func TestSourceApps(t *testing.T) {
 	assert.Equal(t, newName, updatedSourceApp.Name)
 	// Note: Status update is not supported via UpdateSourceAppRequest
 
-	err = client.DeleteSourceApp(testSourceApp.ID)
+	err = client.DeleteSourceApp(ctx, testSourceApp.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete source app: %v", err)
 	}
@@ -148,47 +150,48 @@ func TestSourceAppValidation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
+	ctx := context.Background()
 
 
 	// Test GetSourceAppsByHostingEnvironment with empty ID
-	_, err = client.GetSourceAppsByHostingEnvironment("")
+	_, err = client.GetSourceAppsByHostingEnvironment(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "hosting environment ID cannot be empty")
 
 	// Test GetSourceAppsByHostingEnvironment with invalid UUID
-	_, err = client.GetSourceAppsByHostingEnvironment("invalid-uuid")
+	_, err = client.GetSourceAppsByHostingEnvironment(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test GetSourceApp with empty ID
-	_, err = client.GetSourceApp("")
+	_, err = client.GetSourceApp(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test GetSourceApp with invalid UUID
-	_, err = client.GetSourceApp("invalid-uuid")
+	_, err = client.GetSourceApp(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test UpdateSourceApp with empty ID
 	testName := "test"
 	updateReq := UpdateSourceAppRequest{Name: &testName}
-	_, err = client.UpdateSourceApp("", updateReq)
+	_, err = client.UpdateSourceApp(ctx, "", updateReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test UpdateSourceApp with invalid UUID
-	_, err = client.UpdateSourceApp("invalid-uuid", updateReq)
+	_, err = client.UpdateSourceApp(ctx, "invalid-uuid", updateReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 
 	// Test DeleteSourceApp with empty ID
-	err = client.DeleteSourceApp("")
+	err = client.DeleteSourceApp(ctx, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be empty")
 
 	// Test DeleteSourceApp with invalid UUID
-	err = client.DeleteSourceApp("invalid-uuid")
+	err = client.DeleteSourceApp(ctx, "invalid-uuid")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid UUID format")
 }
\ No newline at end of file
@@ -0,0 +1,149 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+const instrumentationName = "github.com/traceforce/traceforce-go-sdk"
+
+// Span and metric attribute keys.
+const (
+	attrOperation            = attribute.Key("traceforce.operation")
+	attrHostingEnvironmentID = attribute.Key("traceforce.hosting_environment.id")
+	attrDatalakeID           = attribute.Key("traceforce.datalake.id")
+	attrSourceAppID          = attribute.Key("traceforce.source_app.id")
+	attrLinkID               = attribute.Key("traceforce.source_app_datalake_link.id")
+	attrStatusCode           = attribute.Key("http.response.status_code")
+	attrErrorType            = attribute.Key("error.type")
+	attrCacheResult          = attribute.Key("traceforce.cache.result")
+)
+
+// telemetry holds the OpenTelemetry instruments of a client. All of them are
+// no-ops unless a provider is configured in ClientOptions.
+type telemetry struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	requests metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+	cache    metric.Int64Counter
+}
+
+func newTelemetry(options *ClientOptions) (*telemetry, error) {
+	t := &telemetry{}
+
+	tracerProvider := options.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = tracenoop.NewTracerProvider()
+	} else {
+		t.propagator = options.Propagator
+		if t.propagator == nil {
+			t.propagator = propagation.TraceContext{}
+		}
+	}
+	t.tracer = tracerProvider.Tracer(instrumentationName)
+
+	meterProvider := options.MeterProvider
+	if meterProvider == nil {
+		meterProvider = metricnoop.NewMeterProvider()
+	}
+	meter := meterProvider.Meter(instrumentationName)
+
+	var err error
+	t.requests, err = meter.Int64Counter("traceforce.client.requests",
+		metric.WithDescription("Number of Traceforce API operations."))
+	if err != nil {
+		return nil, err
+	}
+	t.errors, err = meter.Int64Counter("traceforce.client.errors",
+		metric.WithDescription("Number of failed Traceforce API operations by error class."))
+	if err != nil {
+		return nil, err
+	}
+	t.duration, err = meter.Float64Histogram("traceforce.client.duration",
+		metric.WithDescription("Duration of Traceforce API operations."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	t.cache, err = meter.Int64Counter("traceforce.client.cache",
+		metric.WithDescription("Number of cacheable Traceforce API reads by cache result."))
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// startOperation starts the span of an SDK operation such as
+// "CreateDatalake". The returned function ends it and records metrics.
+func (t *telemetry) startOperation(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, func(status int, err error)) {
+	start := time.Now()
+	ctx, span := t.tracer.Start(ctx, "traceforce."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	return ctx, func(status int, err error) {
+		metricAttrs := []attribute.KeyValue{attrOperation.String(operation)}
+		if status != 0 {
+			span.SetAttributes(attrStatusCode.Int(status))
+			metricAttrs = append(metricAttrs, attrStatusCode.Int(status))
+		}
+		if err != nil {
+			class := errorClass(status, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, class)
+			metricAttrs = append(metricAttrs, attrErrorType.String(class))
+			t.errors.Add(ctx, 1, metric.WithAttributes(metricAttrs...))
+		}
+		t.requests.Add(ctx, 1, metric.WithAttributes(metricAttrs...))
+		t.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(metricAttrs...))
+		span.End()
+	}
+}
+
+// recordCache counts a cacheable read with its result: hit, revalidated or
+// miss.
+func (t *telemetry) recordCache(ctx context.Context, operation, result string) {
+	t.cache.Add(ctx, 1, metric.WithAttributes(attrOperation.String(operation), attrCacheResult.String(result)))
+}
+
+// inject propagates the trace context of ctx into outgoing headers.
+func (t *telemetry) inject(ctx context.Context, header http.Header) {
+	if t.propagator != nil {
+		t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+	}
+}
+
+// errorClass buckets an operation error for metrics.
+func errorClass(status int, err error) string {
+	switch {
+	case status >= 500:
+		return "server_error"
+	case status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case status >= 400:
+		return "client_error"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case status != 0:
+		return "decode_error"
+	}
+	return "transport_error"
+}
@@ -0,0 +1,113 @@
+package traceforce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTelemetry(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id": "550e8400-e29b-41d4-a716-446655440000"}`))
+	}))
+	defer server.Close()
+
+	spans := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client, err := NewClient("test-key", server.URL, &ClientOptions{
+		TracerProvider: tracerProvider,
+		MeterProvider:  meterProvider,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "parent")
+	id := "550e8400-e29b-41d4-a716-446655440000"
+	_, err = client.GetDatalake(ctx, id)
+	assert.NoError(t, err)
+	err = client.DeleteDatalake(ctx, id)
+	assert.Error(t, err)
+	parent.End()
+
+	ended := spans.Ended()
+	assert.Len(t, ended, 3)
+
+	get := ended[0]
+	assert.Equal(t, "traceforce.GetDatalake", get.Name())
+	assert.Equal(t, parent.SpanContext().TraceID(), get.SpanContext().TraceID())
+	assert.Contains(t, get.Attributes(), attrDatalakeID.String(id))
+	assert.Contains(t, get.Attributes(), attrStatusCode.Int(200))
+
+	del := ended[1]
+	assert.Equal(t, "traceforce.DeleteDatalake", del.Name())
+	assert.Equal(t, codes.Error, del.Status().Code)
+	assert.Equal(t, "client_error", del.Status().Description)
+
+	assert.Contains(t, traceparent, del.SpanContext().TraceID().String())
+
+	var metrics metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &metrics))
+	counts := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				op, _ := point.Attributes.Value(attrOperation)
+				counts[m.Name+"/"+op.AsString()] += point.Value
+			}
+		}
+	}
+	assert.Equal(t, int64(1), counts["traceforce.client.requests/GetDatalake"])
+	assert.Equal(t, int64(1), counts["traceforce.client.requests/DeleteDatalake"])
+	assert.Equal(t, int64(1), counts["traceforce.client.errors/DeleteDatalake"])
+	assert.Equal(t, int64(0), counts["traceforce.client.errors/GetDatalake"])
+}
+
+func TestTelemetryDisabledByDefault(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.GetDatalakes(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, traceparent)
+}
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, "server_error", errorClass(503, assert.AnError))
+	assert.Equal(t, "rate_limited", errorClass(429, assert.AnError))
+	assert.Equal(t, "client_error", errorClass(404, assert.AnError))
+	assert.Equal(t, "timeout", errorClass(0, context.DeadlineExceeded))
+	assert.Equal(t, "decode_error", errorClass(200, assert.AnError))
+	assert.Equal(t, "transport_error", errorClass(0, assert.AnError))
+	assert.Equal(t, "circuit_open", errorClass(0, ErrCircuitOpen))
+}
@@ -0,0 +1,198 @@
+package traceforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// treeExpand is the expand parameter asking the API to embed the children of
+// a hosting environment.
+const treeExpand = "datalakes,source_apps,source_app_datalake_links"
+
+// HostingEnvironmentTree is a hosting environment with its datalakes, source
+// apps and the links between them.
+type HostingEnvironmentTree struct {
+	HostingEnvironment HostingEnvironment      `json:"hosting_environment"`
+	Datalakes          []Datalake              `json:"datalakes"`
+	SourceApps         []SourceApp             `json:"source_apps"`
+	Links              []SourceAppDatalakeLink `json:"source_app_datalake_links"`
+}
+
+// Datalake returns the datalake with the given ID, or nil.
+func (t *HostingEnvironmentTree) Datalake(id string) *Datalake {
+	for i := range t.Datalakes {
+		if t.Datalakes[i].ID == id {
+			return &t.Datalakes[i]
+		}
+	}
+	return nil
+}
+
+// SourceApp returns the source app with the given ID, or nil.
+func (t *HostingEnvironmentTree) SourceApp(id string) *SourceApp {
+	for i := range t.SourceApps {
+		if t.SourceApps[i].ID == id {
+			return &t.SourceApps[i]
+		}
+	}
+	return nil
+}
+
+// DatalakesOf returns the datalakes the given source app is linked to.
+func (t *HostingEnvironmentTree) DatalakesOf(sourceAppID string) []Datalake {
+	var datalakes []Datalake
+	for _, link := range t.Links {
+		if link.SourceAppID != sourceAppID {
+			continue
+		}
+		if datalake := t.Datalake(link.DatalakeID); datalake != nil {
+			datalakes = append(datalakes, *datalake)
+		}
+	}
+	return datalakes
+}
+
+// SourceAppsOf returns the source apps linked to the given datalake.
+func (t *HostingEnvironmentTree) SourceAppsOf(datalakeID string) []SourceApp {
+	var sourceApps []SourceApp
+	for _, link := range t.Links {
+		if link.DatalakeID != datalakeID {
+			continue
+		}
+		if sourceApp := t.SourceApp(link.SourceAppID); sourceApp != nil {
+			sourceApps = append(sourceApps, *sourceApp)
+		}
+	}
+	return sourceApps
+}
+
+// expandedHostingEnvironment is a hosting environment with its children
+// embedded by the expand parameter. Children the API did not embed are nil.
+type expandedHostingEnvironment struct {
+	HostingEnvironment
+	Datalakes  *[]Datalake              `json:"datalakes"`
+	SourceApps *[]SourceApp             `json:"source_apps"`
+	Links      *[]SourceAppDatalakeLink `json:"source_app_datalake_links"`
+}
+
+// GetHostingEnvironmentTree returns the hosting environment with the given
+// ID together with its datalakes, source apps and links. It asks the API to
+// embed them in one response, and fetches them concurrently if the API does
+// not support that.
+func (c *Client) GetHostingEnvironmentTree(ctx context.Context, id string, opts ...CallOption) (tree *HostingEnvironmentTree, err error) {
+	if id == "" {
+		return nil, fmt.Errorf("id cannot be empty")
+	}
+
+	// Validate UUID format
+	_, err = uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UUID format: %v", err)
+	}
+
+	if !c.expandUnsupported.Load() {
+		tree, err = c.getExpandedHostingEnvironment(ctx, id, opts)
+		if tree != nil || err != nil {
+			return tree, err
+		}
+		c.expandUnsupported.Store(true)
+		c.logger.DebugContext(ctx, "traceforce API does not support expand, fetching children separately")
+	}
+
+	ctx, end := c.telemetry.startOperation(ctx, "GetHostingEnvironmentTree", attrHostingEnvironmentID.String(id))
+	defer func() { end(0, err) }()
+	return c.fetchHostingEnvironmentTree(ctx, id, opts)
+}
+
+// getExpandedHostingEnvironment fetches the tree in one request. It returns
+// nil without an error if the API does not support expanding.
+func (c *Client) getExpandedHostingEnvironment(ctx context.Context, id string, opts []CallOption) (*HostingEnvironmentTree, error) {
+	var expanded expandedHostingEnvironment
+	err := c.call(ctx, apiRequest{
+		operation: "GetHostingEnvironmentTree",
+		method:    "GET",
+		path:      "/hosting-environments/" + id + "?expand=" + treeExpand,
+		attrs:     []attribute.KeyValue{attrHostingEnvironmentID.String(id)},
+		opts:      opts,
+	}, &expanded)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expanded.Datalakes == nil || expanded.SourceApps == nil || expanded.Links == nil {
+		return nil, nil
+	}
+
+	return &HostingEnvironmentTree{
+		HostingEnvironment: expanded.HostingEnvironment,
+		Datalakes:          *expanded.Datalakes,
+		SourceApps:         *expanded.SourceApps,
+		Links:              *expanded.Links,
+	}, nil
+}
+
+// fetchHostingEnvironmentTree fetches the environment and its datalakes and
+// source apps concurrently, then the links of every source app.
+func (c *Client) fetchHostingEnvironmentTree(ctx context.Context, id string, opts []CallOption) (*HostingEnvironmentTree, error) {
+	tree := &HostingEnvironmentTree{}
+	var (
+		wg                              sync.WaitGroup
+		envErr, datalakesErr, sourceErr error
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		var environment *HostingEnvironment
+		environment, envErr = c.GetHostingEnvironment(ctx, id, opts...)
+		if envErr == nil {
+			tree.HostingEnvironment = *environment
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		tree.Datalakes, datalakesErr = c.GetDatalakesByHostingEnvironment(ctx, id, opts...)
+	}()
+	go func() {
+		defer wg.Done()
+		tree.SourceApps, sourceErr = c.GetSourceAppsByHostingEnvironment(ctx, id, opts...)
+	}()
+	wg.Wait()
+	if err := errors.Join(envErr, datalakesErr, sourceErr); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(tree.SourceApps))
+	for _, sourceApp := range tree.SourceApps {
+		ids = append(ids, sourceApp.ID)
+	}
+	links := fetchBatch(ctx, c, ids, func(ctx context.Context, sourceAppID string) (*[]SourceAppDatalakeLink, error) {
+		links, err := c.GetSourceAppDatalakeLinksBySourceApp(ctx, sourceAppID, opts...)
+		return &links, err
+	})
+	if err := links.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get source app datalake links: %w", err)
+	}
+	for _, sourceAppID := range ids {
+		tree.Links = append(tree.Links, *links.Items[sourceAppID]...)
+	}
+
+	if tree.Datalakes == nil {
+		tree.Datalakes = []Datalake{}
+	}
+	if tree.SourceApps == nil {
+		tree.SourceApps = []SourceApp{}
+	}
+	if tree.Links == nil {
+		tree.Links = []SourceAppDatalakeLink{}
+	}
+	return tree, nil
+}
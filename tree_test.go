@@ -0,0 +1,78 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHostingEnvironmentTreeFanOut(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	ctx := context.Background()
+
+	env := api.addEnvironment(HostingEnvironment{Name: "prod"})
+	other := api.addEnvironment(HostingEnvironment{Name: "staging"})
+	lake := api.addDatalake(Datalake{HostingEnvironmentID: env.ID, Name: "lake"})
+	archive := api.addDatalake(Datalake{HostingEnvironmentID: env.ID, Name: "archive"})
+	api.addDatalake(Datalake{HostingEnvironmentID: other.ID, Name: "elsewhere"})
+	salesforce := api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Name: "salesforce"})
+	idle := api.addSourceApp(SourceApp{HostingEnvironmentID: env.ID, Name: "idle"})
+	api.addLink(SourceAppDatalakeLink{SourceAppID: salesforce.ID, DatalakeID: lake.ID, HostingEnvironmentID: env.ID})
+	api.addLink(SourceAppDatalakeLink{SourceAppID: salesforce.ID, DatalakeID: archive.ID, HostingEnvironmentID: env.ID})
+
+	tree, err := client.GetHostingEnvironmentTree(ctx, env.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", tree.HostingEnvironment.Name)
+	assert.Len(t, tree.Datalakes, 2)
+	assert.Len(t, tree.SourceApps, 2)
+	assert.Len(t, tree.Links, 2)
+
+	assert.Equal(t, "lake", tree.Datalake(lake.ID).Name)
+	assert.Nil(t, tree.Datalake("missing"))
+	assert.Len(t, tree.DatalakesOf(salesforce.ID), 2)
+	assert.Empty(t, tree.DatalakesOf(idle.ID))
+	if sourceApps := tree.SourceAppsOf(archive.ID); assert.Len(t, sourceApps, 1) {
+		assert.Equal(t, "salesforce", sourceApps[0].Name)
+	}
+
+	// The API ignored expand once, so it is not asked again
+	assert.Equal(t, 2, api.count("GET", "/hosting-environments/"+env.ID))
+	_, err = client.GetHostingEnvironmentTree(ctx, other.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, api.count("GET", "/hosting-environments/"+other.ID))
+
+	_, err = client.GetHostingEnvironmentTree(ctx, "550e8400-e29b-41d4-a716-446655440000")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetHostingEnvironmentTreeExpand(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, treeExpand, r.URL.Query().Get("expand"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":                        "550e8400-e29b-41d4-a716-446655440000",
+			"name":                      "prod",
+			"datalakes":                 []Datalake{{ID: "d1", Name: "lake"}},
+			"source_apps":               []SourceApp{{ID: "s1", Name: "salesforce"}},
+			"source_app_datalake_links": []SourceAppDatalakeLink{{ID: "l1", SourceAppID: "s1", DatalakeID: "d1"}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tree, err := client.GetHostingEnvironmentTree(context.Background(), "550e8400-e29b-41d4-a716-446655440000")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, "prod", tree.HostingEnvironment.Name)
+	assert.Equal(t, "lake", tree.DatalakesOf("s1")[0].Name)
+}
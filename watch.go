@@ -0,0 +1,300 @@
+package traceforce
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls by default.
+const DefaultWatchInterval = 30 * time.Second
+
+// defaultWatchBuffer is the default capacity of the events channel.
+const defaultWatchBuffer = 100
+
+// EventType is the kind of change an Event reports.
+type EventType string
+
+const (
+	EventAdded    EventType = "added"
+	EventModified EventType = "modified"
+	EventDeleted  EventType = "deleted"
+	// EventStatusChanged is a modification that changed the status of a
+	// hosting environment, datalake or source app. It is sent instead of
+	// EventModified.
+	EventStatusChanged EventType = "status_changed"
+)
+
+// Event is a change to a resource seen by a Watcher. Old and New hold a
+// HostingEnvironment, Datalake, SourceApp or SourceAppDatalakeLink value;
+// Old is nil for added resources and New is nil for deleted ones.
+type Event struct {
+	Type EventType
+	// Kind is "hosting_environment", "datalake", "source_app" or
+	// "source_app_datalake_link".
+	Kind string
+	ID   string
+	Old  interface{}
+	New  interface{}
+}
+
+// Object returns the new value of the resource, or the old one if it was
+// deleted.
+func (e Event) Object() interface{} {
+	if e.New != nil {
+		return e.New
+	}
+	return e.Old
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s %s", e.Type, e.Kind, e.ID)
+}
+
+// Snapshot is the state of all resources at a point in time. A Watcher
+// resumed from a snapshot only reports changes made since. Snapshots encode
+// to JSON, so they can be persisted across restarts.
+type Snapshot struct {
+	TakenAt             time.Time               `json:"taken_at"`
+	HostingEnvironments []HostingEnvironment    `json:"hosting_environments"`
+	Datalakes           []Datalake              `json:"datalakes"`
+	SourceApps          []SourceApp             `json:"source_apps"`
+	Links               []SourceAppDatalakeLink `json:"source_app_datalake_links"`
+}
+
+func newSnapshot(inv *inventory, takenAt time.Time) *Snapshot {
+	return &Snapshot{
+		TakenAt:             takenAt,
+		HostingEnvironments: inv.environments,
+		Datalakes:           inv.datalakes,
+		SourceApps:          inv.sourceApps,
+		Links:               inv.links,
+	}
+}
+
+// watchedResource is the part of a resource the watcher compares.
+type watchedResource struct {
+	kind      string
+	id        string
+	status    string
+	updatedAt time.Time
+	value     interface{}
+}
+
+// resources returns the resources of the snapshot keyed by kind and ID.
+func (s *Snapshot) resources() map[string]watchedResource {
+	resources := make(map[string]watchedResource)
+	add := func(r watchedResource) {
+		resources[r.kind+"/"+r.id] = r
+	}
+	for _, e := range s.HostingEnvironments {
+		add(watchedResource{"hosting_environment", e.ID, string(e.Status), e.UpdatedAt, e})
+	}
+	for _, d := range s.Datalakes {
+		add(watchedResource{"datalake", d.ID, string(d.Status), d.UpdatedAt, d})
+	}
+	for _, sa := range s.SourceApps {
+		add(watchedResource{"source_app", sa.ID, string(sa.Status), sa.UpdatedAt, sa})
+	}
+	for _, l := range s.Links {
+		add(watchedResource{"source_app_datalake_link", l.ID, "", l.UpdatedAt, l})
+	}
+	return resources
+}
+
+var resourceKindOrder = map[string]int{"hosting_environment": 0, "datalake": 1, "source_app": 2, "source_app_datalake_link": 3}
+
+// diffSnapshots returns the events that turn old into current. Added and
+// modified parents come before their children, and deleted children before
+// their parents. With resync set, unchanged resources are reported as
+// modified with equal old and new values.
+func diffSnapshots(old, current *Snapshot, resync bool) []Event {
+	before := map[string]watchedResource{}
+	if old != nil {
+		before = old.resources()
+	}
+	after := current.resources()
+
+	var events []Event
+	for key, r := range after {
+		previous, ok := before[key]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventAdded, Kind: r.kind, ID: r.id, New: r.value})
+		case previous.status != r.status:
+			events = append(events, Event{Type: EventStatusChanged, Kind: r.kind, ID: r.id, Old: previous.value, New: r.value})
+		case !previous.updatedAt.Equal(r.updatedAt) || resync:
+			events = append(events, Event{Type: EventModified, Kind: r.kind, ID: r.id, Old: previous.value, New: r.value})
+		}
+	}
+	for key, r := range before {
+		if _, ok := after[key]; !ok {
+			events = append(events, Event{Type: EventDeleted, Kind: r.kind, ID: r.id, Old: r.value})
+		}
+	}
+	rank := func(e Event) int {
+		if e.Type == EventDeleted {
+			return 2*len(resourceKindOrder) - 1 - resourceKindOrder[e.Kind]
+		}
+		return resourceKindOrder[e.Kind]
+	}
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return a.ID < b.ID
+	})
+	return events
+}
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	// Interval is how often the list endpoints are polled, by default
+	// DefaultWatchInterval.
+	Interval time.Duration
+	// ResyncInterval, if set, is how often every resource is reported as
+	// modified, changed or not, so handlers can reconcile periodically.
+	ResyncInterval time.Duration
+	// Snapshot resumes watching from a snapshot returned by
+	// Watcher.Snapshot. Without it every existing resource is reported as
+	// added.
+	Snapshot *Snapshot
+	// BufferSize is the capacity of the events channel, by default 100.
+	BufferSize int
+}
+
+// Watcher reports changes to hosting environments, datalakes, source apps
+// and links.
+type Watcher struct {
+	events chan Event
+	// deliver, if set, receives each poll's events instead of the events
+	// channel.
+	deliver func(ctx context.Context, events []Event) bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu       sync.Mutex
+	snapshot *Snapshot
+}
+
+// Watch polls the list endpoints and sends an event for every change to the
+// channel returned by Events. The API does not offer a change stream, so
+// changes made and reverted between two polls are not seen. Watch returns an
+// error if the first poll fails; later failures are logged and retried at
+// the next interval. The watcher stops when ctx is done or Stop is called.
+func (c *Client) Watch(ctx context.Context, options WatchOptions, opts ...CallOption) (*Watcher, error) {
+	return c.watch(ctx, options, opts, nil)
+}
+
+func (c *Client) watch(ctx context.Context, options WatchOptions, opts []CallOption, deliver func(context.Context, []Event) bool) (*Watcher, error) {
+	interval := options.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	buffer := options.BufferSize
+	if buffer <= 0 {
+		buffer = defaultWatchBuffer
+	}
+	// Revalidate cached lists so polls see changes
+	opts = appendOptions(opts, noCache())
+
+	inv, err := c.fetchInventory(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	current := newSnapshot(inv, time.Now())
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watcher{
+		events:   make(chan Event, buffer),
+		deliver:  deliver,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		snapshot: options.Snapshot,
+	}
+	go w.run(ctx, c, options, interval, opts, options.Snapshot, current)
+	return w, nil
+}
+
+func (w *Watcher) run(ctx context.Context, c *Client, options WatchOptions, interval time.Duration, opts []CallOption, previous, current *Snapshot) {
+	defer close(w.done)
+	defer close(w.events)
+
+	if !w.send(ctx, diffSnapshots(previous, current, false), current) {
+		return
+	}
+	lastResync := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		inv, err := c.fetchInventory(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.WarnContext(ctx, "traceforce watch poll failed", "error", err)
+			continue
+		}
+		previous, current = current, newSnapshot(inv, time.Now())
+		resync := options.ResyncInterval > 0 && time.Since(lastResync) >= options.ResyncInterval
+		if resync {
+			lastResync = time.Now()
+		}
+		if !w.send(ctx, diffSnapshots(previous, current, resync), current) {
+			return
+		}
+	}
+}
+
+// send sends events until ctx is done, reporting whether all were sent.
+// Once they are, snapshot becomes the snapshot of the watcher.
+func (w *Watcher) send(ctx context.Context, events []Event, snapshot *Snapshot) bool {
+	if w.deliver != nil {
+		if !w.deliver(ctx, events) {
+			return false
+		}
+	} else {
+		for _, event := range events {
+			select {
+			case w.events <- event:
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	w.mu.Lock()
+	w.snapshot = snapshot
+	w.mu.Unlock()
+	return true
+}
+
+// Events returns the channel events are sent to. It is closed when the
+// watcher stops. Events are sent in order, so a slow reader delays polling.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Snapshot returns the state of the latest poll whose events were all sent,
+// or the snapshot the watcher resumed from until then. Pass it to
+// WatchOptions.Snapshot to resume watching later; events sent after it was
+// taken may be sent again.
+func (w *Watcher) Snapshot() *Snapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.snapshot
+}
+
+// Stop stops the watcher and waits for the events channel to be closed.
+func (w *Watcher) Stop() {
+	w.cancel()
+	<-w.done
+}
@@ -0,0 +1,118 @@
+package traceforce
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Now()
+	old := &Snapshot{
+		HostingEnvironments: []HostingEnvironment{{ID: "env", Status: HostingEnvironmentStatusConnected, UpdatedAt: now}},
+		Datalakes: []Datalake{
+			{ID: "lake", Status: DatalakeStatusReady, UpdatedAt: now},
+			{ID: "renamed", Name: "a", UpdatedAt: now},
+			{ID: "unchanged", UpdatedAt: now},
+		},
+		Links: []SourceAppDatalakeLink{{ID: "link", UpdatedAt: now}},
+	}
+	current := &Snapshot{
+		HostingEnvironments: []HostingEnvironment{{ID: "env", Status: HostingEnvironmentStatusConnected, UpdatedAt: now}},
+		Datalakes: []Datalake{
+			{ID: "lake", Status: DatalakeStatusFailed, UpdatedAt: now.Add(time.Minute)},
+			{ID: "renamed", Name: "b", UpdatedAt: now.Add(time.Minute)},
+			{ID: "unchanged", UpdatedAt: now},
+		},
+		SourceApps: []SourceApp{{ID: "app"}},
+	}
+
+	events := diffSnapshots(old, current, false)
+	assert.Equal(t, []string{
+		"status_changed datalake lake",
+		"modified datalake renamed",
+		"added source_app app",
+		"deleted source_app_datalake_link link",
+	}, eventStrings(events))
+	assert.Equal(t, DatalakeStatusReady, events[0].Old.(Datalake).Status)
+	assert.Equal(t, DatalakeStatusFailed, events[0].New.(Datalake).Status)
+	assert.Equal(t, "a", events[1].Old.(Datalake).Name)
+	assert.Equal(t, "b", events[1].Object().(Datalake).Name)
+	assert.Equal(t, "link", events[3].Object().(SourceAppDatalakeLink).ID)
+
+	events = diffSnapshots(current, current, true)
+	assert.Len(t, events, 5)
+	for _, event := range events {
+		assert.Equal(t, EventModified, event.Type)
+		assert.Equal(t, event.Old, event.New)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	api := newFakeAPI(t)
+	client := api.client(t)
+	env := api.addEnvironment(HostingEnvironment{Name: "prod", Status: HostingEnvironmentStatusConnected})
+	ctx := context.Background()
+
+	watcher, err := client.Watch(ctx, WatchOptions{Interval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	event := <-watcher.Events()
+	assert.Equal(t, "added hosting_environment "+env.ID, event.String())
+
+	api.mu.Lock()
+	api.environments[env.ID].Status = HostingEnvironmentStatusDisconnected
+	api.environments[env.ID].UpdatedAt = time.Now()
+	api.mu.Unlock()
+	event = <-watcher.Events()
+	assert.Equal(t, EventStatusChanged, event.Type)
+	assert.Equal(t, HostingEnvironmentStatusDisconnected, event.New.(HostingEnvironment).Status)
+
+	// Resuming from a persisted snapshot only reports later changes
+	watcher.Stop()
+	_, open := <-watcher.Events()
+	assert.False(t, open)
+	data, err := json.Marshal(watcher.Snapshot())
+	assert.NoError(t, err)
+	var snapshot Snapshot
+	assert.NoError(t, json.Unmarshal(data, &snapshot))
+
+	lake := api.addDatalake(Datalake{HostingEnvironmentID: env.ID})
+	watcher, err = client.Watch(ctx, WatchOptions{Interval: time.Hour, Snapshot: &snapshot})
+	assert.NoError(t, err)
+	defer watcher.Stop()
+	event = <-watcher.Events()
+	assert.Equal(t, "added datalake "+lake.ID, event.String())
+	select {
+	case event := <-watcher.Events():
+		t.Errorf("unexpected event %s", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchResync(t *testing.T) {
+	api := newFakeAPI(t)
+	api.addEnvironment(HostingEnvironment{Name: "prod"})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	watcher, err := api.client(t).Watch(ctx, WatchOptions{Interval: 10 * time.Millisecond, ResyncInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, EventAdded, (<-watcher.Events()).Type)
+	event := <-watcher.Events()
+	assert.Equal(t, EventModified, event.Type)
+	assert.Equal(t, event.Old, event.New)
+
+	cancel()
+	for range watcher.Events() {
+	}
+}
+
+func eventStrings(events []Event) []string {
+	var s []string
+	for _, event := range events {
+		s = append(s, event.String())
+	}
+	return s
+}